@@ -0,0 +1,335 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicwestvold/gwt/config"
+	"github.com/nicwestvold/gwt/git"
+)
+
+// bulkSelector picks the worktrees `gwt rm` removes in bulk. A worktree must
+// match every criterion that is set.
+type bulkSelector struct {
+	pattern   string        // glob matched against the branch (directory name when detached)
+	merged    bool          // HEAD is reachable from the main branch
+	olderThan time.Duration // no activity (see git.LastActivity) for at least this long
+}
+
+// active reports whether any criterion is set, i.e. `gwt rm` is in bulk mode.
+func (s bulkSelector) active() bool {
+	return s.pattern != "" || s.merged || s.olderThan > 0
+}
+
+// parseBulkRemoveArgs pulls the bulk-removal selectors (a glob pattern,
+// --merged, --older-than <age>) and -y/--yes out of `gwt rm` arguments and
+// returns the rest. A glob cannot be combined with a plain worktree argument.
+func parseBulkRemoveArgs(args []string) (sel bulkSelector, yes bool, rest []string, err error) {
+	rest = []string{}
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--merged":
+			sel.merged = true
+		case a == "-y" || a == "--yes":
+			yes = true
+		case a == "--older-than" || strings.HasPrefix(a, "--older-than="):
+			value, ok := strings.CutPrefix(a, "--older-than=")
+			if !ok {
+				if i+1 >= len(args) {
+					return bulkSelector{}, false, nil, fmt.Errorf("--older-than requires an age, e.g. 30d")
+				}
+				i++
+				value = args[i]
+			}
+			if sel.olderThan, err = parseAge(value); err != nil {
+				return bulkSelector{}, false, nil, err
+			}
+			if sel.olderThan == 0 {
+				return bulkSelector{}, false, nil, fmt.Errorf("--older-than must be positive")
+			}
+		case !strings.HasPrefix(a, "-") && strings.ContainsAny(a, "*?["):
+			if sel.pattern != "" {
+				return bulkSelector{}, false, nil, fmt.Errorf("only one pattern may be given, got %q and %q", sel.pattern, a)
+			}
+			if _, err := path.Match(a, ""); err != nil {
+				return bulkSelector{}, false, nil, fmt.Errorf("invalid pattern %q: %w", a, err)
+			}
+			sel.pattern = a
+		default:
+			if !strings.HasPrefix(a, "-") {
+				positional = append(positional, a)
+			}
+			rest = append(rest, a)
+		}
+	}
+	if sel.active() && len(positional) > 0 {
+		return bulkSelector{}, false, nil, fmt.Errorf("cannot combine worktree %q with --merged, --older-than or a pattern", positional[0])
+	}
+	return sel, yes, rest, nil
+}
+
+// bulkCandidate is a worktree selected for bulk removal.
+type bulkCandidate struct {
+	name   string // branch, or directory name when detached
+	branch string // "" when detached
+	path   string
+	age    time.Duration // time since last activity
+}
+
+// matchBranch reports whether the glob pattern matches name or, segment by
+// segment, its leading "/"-separated segments: "feat/*" matches feat/a and
+// feat/a/b, which path.Match alone would miss.
+func matchBranch(pattern, name string) bool {
+	n := strings.Count(pattern, "/") + 1
+	segments := strings.Split(name, "/")
+	if len(segments) < n {
+		return false
+	}
+	ok, _ := path.Match(pattern, strings.Join(segments[:n], "/"))
+	return ok
+}
+
+// selectWorktrees returns the linked worktrees matching sel, oldest activity
+// first. The main worktree, the main branch's worktree, and locked or missing
+// worktrees are never selected. A worktree still at the main branch's commit
+// has nothing of its own, so --merged does not count it as merged.
+func selectWorktrees(repo *git.Repo, sel bulkSelector, mainBranch string, now time.Time) ([]bulkCandidate, error) {
+	infos, err := repo.ListWorktreesFull()
+	if err != nil {
+		return nil, err
+	}
+	mainRef := git.MainBranchRef(repo.Dir, mainBranch)
+	var out []bulkCandidate
+	for i, w := range infos {
+		if i == 0 || w.Bare || w.Locked || w.Prunable || w.Branch == mainBranch {
+			continue
+		}
+		name, rev := w.Branch, w.Branch
+		if w.Detached {
+			name, rev = filepath.Base(w.Path), w.SHA
+		}
+		if sel.pattern != "" && !matchBranch(sel.pattern, name) {
+			continue
+		}
+		if sel.merged && (git.SameCommit(repo.Dir, rev, mainRef) || !git.IsMerged(repo.Dir, rev, mainRef)) {
+			continue
+		}
+		last, err := git.LastActivity(w.Path)
+		if err != nil {
+			continue
+		}
+		age := now.Sub(last)
+		if age < sel.olderThan {
+			continue
+		}
+		out = append(out, bulkCandidate{name: name, branch: w.Branch, path: w.Path, age: age})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].age > out[j].age })
+	return out, nil
+}
+
+// runBulkRemove removes (or, with trash, trashes) every worktree matching
+// sel after listing them and asking for confirmation once (skipped by yes).
+// Unless opts.Force, worktrees holding work that would be lost are listed and
+// left alone. Worktrees are removed concurrently; branches and remote
+// branches are then deleted one by one, and the results are summarized.
+func runBulkRemove(p prompter, repo *git.Repo, sel bulkSelector, yes bool, opts git.RemoveOptions, trash bool) error {
+	mainBranch := ""
+	if name, err := repo.CanonicalName(); err == nil {
+		if cfg, err := config.Load(); err == nil {
+			if entry, ok := cfg.Lookup(name); ok {
+				mainBranch = entry.MainBranch
+			}
+		}
+	}
+	mainBranch = git.ResolveLocalMainBranch(repo.Dir, mainBranch)
+
+	candidates, err := selectWorktrees(repo, sel, mainBranch, time.Now())
+	if err != nil {
+		return err
+	}
+	var targets []bulkCandidate
+	var atRisk []string
+	for _, c := range candidates {
+		var lossErr *git.WorkLossError
+		if !opts.Force && !trash && errors.As(git.CheckRemovable(c.path), &lossErr) {
+			atRisk = append(atRisk, "  "+c.name+"\n"+indent(lossErr.Loss.Summary(), "  "))
+			continue
+		}
+		targets = append(targets, c)
+	}
+	if len(atRisk) > 0 {
+		fmt.Fprintf(p.out, "Skipping %d worktree(s) with work that would be lost (use --force to include them):\n%s", len(atRisk), strings.Join(atRisk, ""))
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(p.out, "no worktrees to remove")
+		return nil
+	}
+
+	verb := "Remove"
+	if trash {
+		verb = "Move to the trash"
+	}
+	nameW := 0
+	for _, c := range targets {
+		nameW = max(nameW, len(c.name))
+	}
+	fmt.Fprintf(p.out, "%s %d worktree(s):\n", verb, len(targets))
+	for _, c := range targets {
+		fmt.Fprintf(p.out, "  %-*s  %s  (last active %s ago)\n", nameW, c.name, c.path, git.FormatAge(c.age))
+	}
+	if !yes {
+		if !stdinIsTerminal() {
+			return fmt.Errorf("refusing to remove %d worktrees without confirmation; pass --yes", len(targets))
+		}
+		ok, err := p.confirm(verb+"?", false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(p.out, "nothing removed")
+			return nil
+		}
+	}
+
+	removedPaths := make([]string, 0, len(targets))
+	defer func() { cdIfRemoved(repo.Dir, removedPaths) }()
+
+	if trash {
+		trashDir, err := config.TrashDir()
+		if err != nil {
+			return err
+		}
+		var failures []string
+		for _, c := range targets {
+			if _, err := repo.Trash([]string{c.path}, trashDir); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", c.name, err))
+				continue
+			}
+			removedPaths = append(removedPaths, c.path)
+		}
+		fmt.Fprintf(p.out, "moved %d/%d worktrees to the trash — restore with 'gwt restore <name>'\n", len(removedPaths), len(targets))
+		for _, f := range failures {
+			fmt.Fprintf(p.out, "  ! %s\n", f)
+		}
+		if len(failures) > 0 {
+			return fmt.Errorf("%d of %d worktrees could not be trashed", len(failures), len(targets))
+		}
+		return nil
+	}
+
+	// Remove the worktrees concurrently; branch and remote deletions edit
+	// shared refs and config, so they run one at a time afterwards.
+	results := make([]removalOutcome, len(targets))
+	var wg sync.WaitGroup
+	for i, c := range targets {
+		wg.Add(1)
+		go func(i int, c bulkCandidate) {
+			defer wg.Done()
+			results[i] = removalOutcome{
+				name:    c.name,
+				present: true,
+				mr:      git.RemoveMemberWorktree(repo.Dir, c.path, git.RemoveOptions{KeepBranch: true, Force: opts.Force}),
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	var keptBranches, deletedRemotes, keptRemotes []string
+	worktreeRoot := ""
+	if dataDir, err := config.DataDir(); err == nil {
+		worktreeRoot = filepath.Join(dataDir, "worktrees")
+	}
+	for i, c := range targets {
+		res := &results[i]
+		if res.mr.Err != nil {
+			continue
+		}
+		removedPaths = append(removedPaths, c.path)
+		if worktreeRoot != "" && strings.HasPrefix(c.path, worktreeRoot+string(filepath.Separator)) {
+			git.CleanEmptyParents(filepath.Dir(c.path), worktreeRoot)
+		}
+		if c.branch == "" {
+			continue
+		}
+		if opts.Remote {
+			res.mr.Remote = git.DeleteRemoteBranch(repo.Dir, c.branch, opts.ForceRemote)
+			if res.mr.Remote.Deleted != "" {
+				deletedRemotes = append(deletedRemotes, res.mr.Remote.Deleted)
+			}
+			if res.mr.Remote.Kept != "" {
+				keptRemotes = append(keptRemotes, res.mr.Remote.Kept)
+			}
+		}
+		if !opts.KeepBranch {
+			if err := git.DeleteBranch(repo.Dir, c.branch); err != nil {
+				keptBranches = append(keptBranches, c.branch)
+			}
+		}
+	}
+
+	removed, attempted, freed, failures := removalTotals(results)
+	if len(failures) == 0 {
+		fmt.Fprintf(p.out, "removed %d worktrees — freed %s\n", removed, freed)
+	} else {
+		fmt.Fprintf(p.out, "removed %d/%d worktrees — freed %s\n", removed, attempted, freed)
+		for _, f := range failures {
+			fmt.Fprintf(p.out, "  ! %s\n", f)
+		}
+	}
+	if len(keptBranches) > 0 {
+		fmt.Fprintf(p.out, "note: branches kept (not fully merged): %s\n", strings.Join(keptBranches, ", "))
+		fmt.Fprintln(p.out, "      delete with: git branch -D <branch>")
+	}
+	if len(deletedRemotes) > 0 {
+		fmt.Fprintf(p.out, "deleted remote branches: %s\n", strings.Join(deletedRemotes, ", "))
+	}
+	for _, k := range keptRemotes {
+		fmt.Fprintf(p.out, "note: %s\n", k)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d worktrees could not be removed", len(failures), attempted)
+	}
+	return nil
+}
+
+// cdIfRemoved forgets the removed worktrees' visits and sends the shell
+// wrapper back to repoDir when the current directory was inside one of them
+// (or no longer exists).
+func cdIfRemoved(repoDir string, removed []string) {
+	if len(removed) > 0 {
+		forgetVisits(removed...)
+	}
+	cwd, err := os.Getwd()
+	if err == nil {
+		if resolved, err := filepath.EvalSymlinks(cwd); err == nil {
+			cwd = resolved
+		}
+	}
+	for _, p := range removed {
+		if err != nil || cwd == p || strings.HasPrefix(cwd, p+string(filepath.Separator)) {
+			leaveTo(repoDir)
+			return
+		}
+	}
+}
+
+// indent prefixes every non-empty line of s with prefix.
+func indent(s, prefix string) string {
+	lines := strings.SplitAfter(s, "\n")
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, "")
+}
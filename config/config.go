@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -17,12 +18,15 @@ type RepoEntry struct {
 	VersionManager string   `toml:"version_manager,omitempty"`
 	CopyFiles      []string `toml:"copy_files,omitempty"`
 	MainBranch     string   `toml:"main_branch,omitempty"`
+	Sparse         []string `toml:"sparse,omitempty"` // cone-mode directories checked out in new worktrees
 }
 
 // Config is the top-level gwt configuration, keyed by canonical repo name.
 type Config struct {
-	Repos      map[string]RepoEntry      `toml:"repos"`
-	Workspaces map[string]WorkspaceEntry `toml:"workspaces,omitempty"`
+	Trash        bool                      `toml:"trash,omitempty"`         // gwt rm moves worktrees to the trash by default
+	PromptFormat string                    `toml:"prompt_format,omitempty"` // default template for gwt prompt
+	Repos        map[string]RepoEntry      `toml:"repos"`
+	Workspaces   map[string]WorkspaceEntry `toml:"workspaces,omitempty"`
 }
 
 func ConfigDir() (string, error) {
@@ -47,6 +51,29 @@ func DataDir() (string, error) {
 	return filepath.Join(home, ".local", "share", "gwt"), nil
 }
 
+// CacheDir holds data gwt can rebuild at any time, such as gwt prompt's
+// dirty-state cache.
+func CacheDir() (string, error) {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "gwt"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".cache", "gwt"), nil
+}
+
+// TrashDir is where gwt rm --trash keeps removed worktrees until restored or
+// purged.
+func TrashDir() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "trash"), nil
+}
+
 func configPath() (string, error) {
 	dir, err := ConfigDir()
 	if err != nil {
@@ -118,6 +145,21 @@ func (c *Config) Register(name string, entry RepoEntry) {
 	c.Repos[name] = entry
 }
 
+// Unregister removes a repo from the config. It is a no-op for unknown names.
+func (c *Config) Unregister(name string) {
+	delete(c.Repos, name)
+}
+
+// EncodeRepo renders a single [repos] entry exactly as Save would write it.
+func EncodeRepo(name string, entry RepoEntry) (string, error) {
+	var buf bytes.Buffer
+	doc := map[string]map[string]RepoEntry{"repos": {name: entry}}
+	if err := toml.NewEncoder(&buf).Encode(doc); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 // Equal reports whether two RepoEntry values are identical.
 func (e RepoEntry) Equal(other RepoEntry) bool {
 	return e.Path == other.Path &&
@@ -125,5 +167,6 @@ func (e RepoEntry) Equal(other RepoEntry) bool {
 		e.PackageManager == other.PackageManager &&
 		e.VersionManager == other.VersionManager &&
 		e.MainBranch == other.MainBranch &&
-		(len(e.CopyFiles) == 0 && len(other.CopyFiles) == 0 || slices.Equal(e.CopyFiles, other.CopyFiles))
+		(len(e.CopyFiles) == 0 && len(other.CopyFiles) == 0 || slices.Equal(e.CopyFiles, other.CopyFiles)) &&
+		(len(e.Sparse) == 0 && len(other.Sparse) == 0 || slices.Equal(e.Sparse, other.Sparse))
 }
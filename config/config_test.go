@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -10,7 +11,7 @@ func TestLoadSaveRoundTrip(t *testing.T) {
 	tmp := t.TempDir()
 	t.Setenv("XDG_CONFIG_HOME", tmp)
 
-	cfg := &Config{Repos: map[string]RepoEntry{
+	cfg := &Config{Trash: true, Repos: map[string]RepoEntry{
 		"acme/dashboard": {
 			Path:           "/home/user/code/metrics-drilldown",
 			Bare:           true,
@@ -36,6 +37,9 @@ func TestLoadSaveRoundTrip(t *testing.T) {
 	if len(loaded.Repos) != 2 {
 		t.Fatalf("got %d repos, want 2", len(loaded.Repos))
 	}
+	if !loaded.Trash {
+		t.Error("Trash = false, want true")
+	}
 
 	entry, ok := loaded.Lookup("acme/dashboard")
 	if !ok {
@@ -118,6 +122,39 @@ func TestRegisterAndLookup(t *testing.T) {
 	if entry.Path != "/some/path" {
 		t.Errorf("Path = %q, want %q", entry.Path, "/some/path")
 	}
+
+	cfg.Unregister("owner/repo")
+	if _, ok := cfg.Lookup("owner/repo"); ok {
+		t.Error("Lookup should return false after Unregister")
+	}
+}
+
+func TestRepoEntryEqualSparse(t *testing.T) {
+	a := RepoEntry{Path: "/p", Sparse: []string{"app", "lib"}}
+	if !a.Equal(RepoEntry{Path: "/p", Sparse: []string{"app", "lib"}}) {
+		t.Error("identical sparse profiles should be equal")
+	}
+	if a.Equal(RepoEntry{Path: "/p", Sparse: []string{"app"}}) {
+		t.Error("different sparse profiles should not be equal")
+	}
+	if !(RepoEntry{Path: "/p", Sparse: []string{}}).Equal(RepoEntry{Path: "/p"}) {
+		t.Error("empty and nil sparse profiles should be equal")
+	}
+}
+
+func TestEncodeRepo(t *testing.T) {
+	got, err := EncodeRepo("owner/repo", RepoEntry{Path: "/some/path", Bare: true, MainBranch: "main"})
+	if err != nil {
+		t.Fatalf("EncodeRepo() error: %v", err)
+	}
+	for _, want := range []string{`[repos."owner/repo"]`, `path = "/some/path"`, "bare = true", `main_branch = "main"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("EncodeRepo() missing %q\n---\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "package_manager") {
+		t.Errorf("EncodeRepo() should omit empty fields\n---\n%s", got)
+	}
 }
 
 func TestConfigDir(t *testing.T) {
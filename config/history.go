@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// maxVisits caps how many worktrees the history remembers; the least recently
+// visited are forgotten first.
+const maxVisits = 500
+
+// Visit records how often and how recently a worktree was switched to.
+type Visit struct {
+	Path  string    `toml:"path"`
+	Count int       `toml:"count"`
+	Last  time.Time `toml:"last"`
+}
+
+// History is the record of worktree visits: where gwt add, use and jump
+// sent the shell. gwt use - returns to the previous one, gwt jump ranks by
+// it, and gwt ls --sort=recent shows it. gwt rm forgets the visits to the
+// worktrees it removes rather than recording where it sends the shell.
+type History struct {
+	Visits []Visit `toml:"visit"`
+}
+
+func historyPath() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.toml"), nil
+}
+
+// LoadHistory reads the visit history, which is empty until the first visit.
+func LoadHistory() (*History, error) {
+	h := &History{}
+	p, err := historyPath()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := toml.DecodeFile(p, h); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read visit history: %w", err)
+	}
+	return h, nil
+}
+
+// Save writes the history atomically, keeping only the maxVisits most recent
+// visits.
+func (h *History) Save() error {
+	p, err := historyPath()
+	if err != nil {
+		return err
+	}
+	h.Visits = h.Recent()
+	if len(h.Visits) > maxVisits {
+		h.Visits = h.Visits[:maxVisits]
+	}
+	dir := filepath.Dir(p)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "history-*.toml")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if err := toml.NewEncoder(tmp).Encode(h); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	return os.Rename(tmpName, p)
+}
+
+// Record counts a visit to the worktree at path at time now.
+func (h *History) Record(path string, now time.Time) {
+	for i := range h.Visits {
+		if h.Visits[i].Path == path {
+			h.Visits[i].Count++
+			h.Visits[i].Last = now
+			return
+		}
+	}
+	h.Visits = append(h.Visits, Visit{Path: path, Count: 1, Last: now})
+}
+
+// Frecency scores every visited path by how often and how recently it was
+// visited: each visit counts 4 within the hour, 2 within the day, 1 within
+// the week and 1/4 after that, judged by the latest visit.
+func (h *History) Frecency(now time.Time) map[string]float64 {
+	scores := make(map[string]float64, len(h.Visits))
+	for _, v := range h.Visits {
+		weight := 0.25
+		switch age := now.Sub(v.Last); {
+		case age < time.Hour:
+			weight = 4
+		case age < 24*time.Hour:
+			weight = 2
+		case age < 7*24*time.Hour:
+			weight = 1
+		}
+		scores[v.Path] = float64(v.Count) * weight
+	}
+	return scores
+}
+
+// Forget drops the visits to the given paths, e.g. worktrees that were
+// removed.
+func (h *History) Forget(paths ...string) {
+	h.Visits = slices.DeleteFunc(h.Visits, func(v Visit) bool { return slices.Contains(paths, v.Path) })
+}
+
+// Recent returns the visits, most recent first.
+func (h *History) Recent() []Visit {
+	out := slices.Clone(h.Visits)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Last.After(out[j].Last) })
+	return out
+}
+
+// LastUsed maps every visited path to when it was last visited.
+func (h *History) LastUsed() map[string]time.Time {
+	m := make(map[string]time.Time, len(h.Visits))
+	for _, v := range h.Visits {
+		m[v.Path] = v.Last
+	}
+	return m
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoryRecordAndFrecency(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	h, err := LoadHistory()
+	if err != nil {
+		t.Fatalf("LoadHistory() error: %v", err)
+	}
+	if len(h.Visits) != 0 {
+		t.Fatalf("new history has %d visits, want 0", len(h.Visits))
+	}
+
+	now := time.Now()
+	h.Record("/wt/old", now.Add(-30*24*time.Hour))
+	h.Record("/wt/old", now.Add(-30*24*time.Hour))
+	h.Record("/wt/old", now.Add(-30*24*time.Hour))
+	h.Record("/wt/recent", now.Add(-time.Minute))
+	if err := h.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	loaded, err := LoadHistory()
+	if err != nil {
+		t.Fatalf("LoadHistory() error: %v", err)
+	}
+	if len(loaded.Visits) != 2 || loaded.Visits[0].Path != "/wt/recent" || loaded.Visits[1].Count != 3 {
+		t.Fatalf("loaded visits = %+v", loaded.Visits)
+	}
+	scores := loaded.Frecency(now)
+	if scores["/wt/recent"] != 4 || scores["/wt/old"] != 0.75 {
+		t.Errorf("Frecency() = %v, want recent 4 and old 0.75", scores)
+	}
+}
+
+func TestHistoryForgetAndRecent(t *testing.T) {
+	now := time.Now()
+	h := &History{}
+	h.Record("/wt/a", now.Add(-2*time.Hour))
+	h.Record("/wt/b", now)
+	h.Record("/wt/c", now.Add(-time.Hour))
+	h.Forget("/wt/c", "/wt/missing")
+
+	recent := h.Recent()
+	if len(recent) != 2 || recent[0].Path != "/wt/b" || recent[1].Path != "/wt/a" {
+		t.Errorf("Recent() = %+v, want b then a", recent)
+	}
+	if used := h.LastUsed(); !used["/wt/b"].Equal(now) || len(used) != 2 {
+		t.Errorf("LastUsed() = %v", used)
+	}
+}
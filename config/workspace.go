@@ -54,13 +54,14 @@ type ResolvedMember struct {
 	Name       string // canonical name as registered, e.g. "owner/repo"
 	Short      string // last segment, used as the sibling directory name
 	Path       string // repo path on disk
-	MainBranch string // defaults to "main"
+	MainBranch string // "" when not registered; see git.ResolveMainBranch
+	Sparse     []string
 	IsPrimary  bool
 }
 
-// resolveMember maps a member reference (canonical or unique short segment)
-// to its registered repo entry.
-func (c *Config) resolveMember(ref string) (string, RepoEntry, error) {
+// ResolveRepo maps a repo reference (canonical name or unique short last
+// segment) to its registered canonical name and entry.
+func (c *Config) ResolveRepo(ref string) (string, RepoEntry, error) {
 	if e, ok := c.Repos[ref]; ok {
 		return ref, e, nil
 	}
@@ -75,10 +76,36 @@ func (c *Config) resolveMember(ref string) (string, RepoEntry, error) {
 	case 1:
 		return matches[0], c.Repos[matches[0]], nil
 	case 0:
-		return "", RepoEntry{}, fmt.Errorf("member %q not registered in [repos]; run gwt init there", ref)
+		return "", RepoEntry{}, fmt.Errorf("%q not registered in [repos]; run gwt init there", ref)
 	default:
-		return "", RepoEntry{}, fmt.Errorf("member %q is ambiguous; matches %v — use the full canonical name", ref, matches)
+		return "", RepoEntry{}, fmt.Errorf("%q is ambiguous; matches %v — use the full canonical name", ref, matches)
+	}
+}
+
+// resolveMember maps a member reference to its registered repo entry.
+func (c *Config) resolveMember(ref string) (string, RepoEntry, error) {
+	name, entry, err := c.ResolveRepo(ref)
+	if err != nil {
+		return "", RepoEntry{}, fmt.Errorf("member %w", err)
+	}
+	return name, entry, nil
+}
+
+// WorkspacesReferencing returns the sorted names of workspaces that list the
+// canonical repo name as a member, by canonical name or short segment.
+func (c *Config) WorkspacesReferencing(canonical string) []string {
+	short := lastSegment(canonical)
+	var names []string
+	for name, ws := range c.Workspaces {
+		for _, m := range ws.Members {
+			if m == canonical || m == short {
+				names = append(names, name)
+				break
+			}
+		}
 	}
+	sort.Strings(names)
+	return names
 }
 
 // ResolveMembers resolves every member of a workspace, marking the primary.
@@ -101,15 +128,12 @@ func (c *Config) ResolveMembers(ws WorkspaceEntry) ([]ResolvedMember, error) {
 		if err != nil {
 			return nil, err
 		}
-		mb := entry.MainBranch
-		if mb == "" {
-			mb = "main"
-		}
 		out = append(out, ResolvedMember{
 			Name:       canon,
 			Short:      lastSegment(canon),
 			Path:       entry.Path,
-			MainBranch: mb,
+			MainBranch: entry.MainBranch,
+			Sparse:     entry.Sparse,
 			IsPrimary:  (primaryCanon == "" && i == 0) || canon == primaryCanon,
 		})
 	}
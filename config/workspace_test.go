@@ -113,8 +113,8 @@ func TestResolveMembers(t *testing.T) {
 	if members[1].Short != "app-plugins" || members[1].IsPrimary {
 		t.Errorf("member[1] = %+v", members[1])
 	}
-	if members[1].MainBranch != "main" {
-		t.Errorf("member[1].MainBranch = %q, want defaulted main", members[1].MainBranch)
+	if members[1].MainBranch != "" {
+		t.Errorf("member[1].MainBranch = %q, want empty (resolved from the repo at use)", members[1].MainBranch)
 	}
 }
 
@@ -146,6 +146,52 @@ func TestResolveMembersErrors(t *testing.T) {
 	}
 }
 
+func TestResolveRepo(t *testing.T) {
+	cfg := &Config{Repos: map[string]RepoEntry{
+		"a/app":  {Path: "/a/app"},
+		"b/app":  {Path: "/b/app"},
+		"x/only": {Path: "/x/only"},
+	}}
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"x/only", "x/only", false},
+		{"only", "x/only", false},
+		{"a/app", "a/app", false},
+		{"app", "", true},
+		{"missing", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, _, err := cfg.ResolveRepo(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveRepo(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveRepo(%q) = %q, want %q", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkspacesReferencing(t *testing.T) {
+	cfg := &Config{Workspaces: map[string]WorkspaceEntry{
+		"web":   {Members: []string{"app", "acme/app-plugins"}},
+		"tools": {Members: []string{"acme/app", "cli"}},
+		"other": {Members: []string{"lib"}},
+	}}
+	got := cfg.WorkspacesReferencing("acme/app")
+	if len(got) != 2 || got[0] != "tools" || got[1] != "web" {
+		t.Errorf("WorkspacesReferencing(acme/app) = %v, want [tools web]", got)
+	}
+	if got := cfg.WorkspacesReferencing("acme/unused"); len(got) != 0 {
+		t.Errorf("WorkspacesReferencing(acme/unused) = %v, want none", got)
+	}
+}
+
 func TestResolveWorktreeRoot(t *testing.T) {
 	t.Run("explicit with tilde", func(t *testing.T) {
 		home, _ := os.UserHomeDir()
@@ -20,9 +20,12 @@ type FileSource interface {
 }
 
 // Result holds the detected managers. Empty string means "not detected".
+// The *Signal fields name the file each manager was inferred from.
 type Result struct {
-	VersionManager string
-	PackageManager string
+	VersionManager       string
+	VersionManagerSignal string
+	PackageManager       string
+	PackageManagerSignal string
 }
 
 var validPackageManagers = map[string]bool{"pnpm": true, "npm": true, "yarn": true}
@@ -31,30 +34,34 @@ var validPackageManagers = map[string]bool{"pnpm": true, "npm": true, "yarn": tr
 // disambiguate a bare .tool-versions file (mise and asdf share it); pass
 // exec.LookPath in production.
 func Detect(src FileSource, lookPath func(string) (string, error)) Result {
-	return Result{
-		VersionManager: detectVersionManager(src, lookPath),
-		PackageManager: detectPackageManager(src),
-	}
+	var r Result
+	r.VersionManager, r.VersionManagerSignal = detectVersionManager(src, lookPath)
+	r.PackageManager, r.PackageManagerSignal = detectPackageManager(src)
+	return r
 }
 
-func detectVersionManager(src FileSource, lookPath func(string) (string, error)) string {
+// detectVersionManager returns the version manager and the signal file it was
+// inferred from, or two empty strings.
+func detectVersionManager(src FileSource, lookPath func(string) (string, error)) (string, string) {
 	for _, f := range []string{"mise.toml", ".mise.toml", ".config/mise/config.toml"} {
 		if src.Exists(f) {
-			return "mise"
+			return "mise", f
 		}
 	}
 	if src.Exists(".tool-versions") {
 		if _, err := lookPath("mise"); err == nil {
-			return "mise"
+			return "mise", ".tool-versions"
 		}
 		if _, err := lookPath("asdf"); err == nil {
-			return "asdf"
+			return "asdf", ".tool-versions"
 		}
 	}
-	return ""
+	return "", ""
 }
 
-func detectPackageManager(src FileSource) string {
+// detectPackageManager returns the package manager and the signal file it was
+// inferred from, or two empty strings.
+func detectPackageManager(src FileSource) (string, string) {
 	if src.Exists("package.json") {
 		if data, err := src.Read("package.json"); err == nil {
 			var pj struct {
@@ -66,7 +73,7 @@ func detectPackageManager(src FileSource) string {
 					name = name[:i]
 				}
 				if validPackageManagers[name] {
-					return name
+					return name, "package.json"
 				}
 			}
 		}
@@ -77,10 +84,10 @@ func detectPackageManager(src FileSource) string {
 		{"package-lock.json", "npm"},
 	} {
 		if src.Exists(lf.file) {
-			return lf.pm
+			return lf.pm, lf.file
 		}
 	}
-	return ""
+	return "", ""
 }
 
 // DirSource reads detection signals from a directory on disk. Used when the
@@ -43,21 +43,22 @@ func TestDetectVersionManager(t *testing.T) {
 		files     map[string]string
 		available []string
 		want      string
+		signal    string
 	}{
-		{"mise.toml", map[string]string{"mise.toml": ""}, nil, "mise"},
-		{"dot mise.toml", map[string]string{".mise.toml": ""}, nil, "mise"},
-		{"config mise", map[string]string{".config/mise/config.toml": ""}, nil, "mise"},
-		{"tool-versions with mise on PATH", map[string]string{".tool-versions": ""}, []string{"mise"}, "mise"},
-		{"tool-versions with only asdf on PATH", map[string]string{".tool-versions": ""}, []string{"asdf"}, "asdf"},
-		{"tool-versions with mise and asdf prefers mise", map[string]string{".tool-versions": ""}, []string{"mise", "asdf"}, "mise"},
-		{"tool-versions with neither installed", map[string]string{".tool-versions": ""}, nil, ""},
-		{"nothing", map[string]string{}, nil, ""},
+		{"mise.toml", map[string]string{"mise.toml": ""}, nil, "mise", "mise.toml"},
+		{"dot mise.toml", map[string]string{".mise.toml": ""}, nil, "mise", ".mise.toml"},
+		{"config mise", map[string]string{".config/mise/config.toml": ""}, nil, "mise", ".config/mise/config.toml"},
+		{"tool-versions with mise on PATH", map[string]string{".tool-versions": ""}, []string{"mise"}, "mise", ".tool-versions"},
+		{"tool-versions with only asdf on PATH", map[string]string{".tool-versions": ""}, []string{"asdf"}, "asdf", ".tool-versions"},
+		{"tool-versions with mise and asdf prefers mise", map[string]string{".tool-versions": ""}, []string{"mise", "asdf"}, "mise", ".tool-versions"},
+		{"tool-versions with neither installed", map[string]string{".tool-versions": ""}, nil, "", ""},
+		{"nothing", map[string]string{}, nil, "", ""},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := detectVersionManager(fakeSource{tt.files}, lookPathWith(tt.available...))
-			if got != tt.want {
-				t.Errorf("detectVersionManager() = %q, want %q", got, tt.want)
+			got, signal := detectVersionManager(fakeSource{tt.files}, lookPathWith(tt.available...))
+			if got != tt.want || signal != tt.signal {
+				t.Errorf("detectVersionManager() = (%q, %q), want (%q, %q)", got, signal, tt.want, tt.signal)
 			}
 		})
 	}
@@ -65,26 +66,27 @@ func TestDetectVersionManager(t *testing.T) {
 
 func TestDetectPackageManager(t *testing.T) {
 	tests := []struct {
-		name  string
-		files map[string]string
-		want  string
+		name   string
+		files  map[string]string
+		want   string
+		signal string
 	}{
-		{"packageManager field pnpm", map[string]string{"package.json": `{"packageManager":"pnpm@8.15.0"}`}, "pnpm"},
-		{"packageManager field yarn", map[string]string{"package.json": `{"packageManager":"yarn@4.1.0"}`}, "yarn"},
-		{"packageManager field npm", map[string]string{"package.json": `{"packageManager":"npm@10.0.0"}`}, "npm"},
-		{"unsupported field falls through to lockfile", map[string]string{"package.json": `{"packageManager":"bun@1.0.0"}`, "yarn.lock": ""}, "yarn"},
-		{"pnpm lockfile", map[string]string{"pnpm-lock.yaml": ""}, "pnpm"},
-		{"yarn lockfile", map[string]string{"yarn.lock": ""}, "yarn"},
-		{"npm lockfile", map[string]string{"package-lock.json": ""}, "npm"},
-		{"multiple lockfiles prefer pnpm", map[string]string{"pnpm-lock.yaml": "", "yarn.lock": "", "package-lock.json": ""}, "pnpm"},
-		{"package.json without field, no lockfile", map[string]string{"package.json": `{"name":"x"}`}, ""},
-		{"nothing", map[string]string{}, ""},
+		{"packageManager field pnpm", map[string]string{"package.json": `{"packageManager":"pnpm@8.15.0"}`}, "pnpm", "package.json"},
+		{"packageManager field yarn", map[string]string{"package.json": `{"packageManager":"yarn@4.1.0"}`}, "yarn", "package.json"},
+		{"packageManager field npm", map[string]string{"package.json": `{"packageManager":"npm@10.0.0"}`}, "npm", "package.json"},
+		{"unsupported field falls through to lockfile", map[string]string{"package.json": `{"packageManager":"bun@1.0.0"}`, "yarn.lock": ""}, "yarn", "yarn.lock"},
+		{"pnpm lockfile", map[string]string{"pnpm-lock.yaml": ""}, "pnpm", "pnpm-lock.yaml"},
+		{"yarn lockfile", map[string]string{"yarn.lock": ""}, "yarn", "yarn.lock"},
+		{"npm lockfile", map[string]string{"package-lock.json": ""}, "npm", "package-lock.json"},
+		{"multiple lockfiles prefer pnpm", map[string]string{"pnpm-lock.yaml": "", "yarn.lock": "", "package-lock.json": ""}, "pnpm", "pnpm-lock.yaml"},
+		{"package.json without field, no lockfile", map[string]string{"package.json": `{"name":"x"}`}, "", ""},
+		{"nothing", map[string]string{}, "", ""},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := detectPackageManager(fakeSource{tt.files})
-			if got != tt.want {
-				t.Errorf("detectPackageManager() = %q, want %q", got, tt.want)
+			got, signal := detectPackageManager(fakeSource{tt.files})
+			if got != tt.want || signal != tt.signal {
+				t.Errorf("detectPackageManager() = (%q, %q), want (%q, %q)", got, signal, tt.want, tt.signal)
 			}
 		})
 	}
@@ -99,6 +101,9 @@ func TestDetect(t *testing.T) {
 	if got.VersionManager != "mise" || got.PackageManager != "pnpm" {
 		t.Errorf("Detect() = %+v, want {mise pnpm}", got)
 	}
+	if got.VersionManagerSignal != "mise.toml" || got.PackageManagerSignal != "pnpm-lock.yaml" {
+		t.Errorf("Detect() signals = %+v, want mise.toml and pnpm-lock.yaml", got)
+	}
 }
 
 func TestDirSource(t *testing.T) {
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/nicwestvold/gwt/config"
+	"github.com/nicwestvold/gwt/git"
+)
+
+// execTarget is a worktree `gwt exec` runs its command in.
+type execTarget struct {
+	name string // branch, member name in a workspace group, or directory name when detached
+	dir  string
+}
+
+// execResult is the outcome of running the command in one target.
+type execResult struct {
+	target  execTarget
+	code    int   // exit code; -1 when the command could not be started
+	err     error // nil on success
+	elapsed time.Duration
+}
+
+// execTargets returns the worktrees `gwt exec` runs in: the member worktrees
+// of the workspace group containing the current directory, or else every
+// worktree of repo (bare and missing ones excepted). Only names matching the
+// glob filter are kept when it is set, as gwt rm matches them (matchBranch).
+func execTargets(repo *git.Repo, filter string) ([]execTarget, error) {
+	targets, ok, err := workspaceGroupTargets(repo)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		infos, err := repo.ListWorktreesFull()
+		if err != nil {
+			return nil, err
+		}
+		for _, w := range infos {
+			if w.Bare || w.Prunable {
+				continue
+			}
+			name := w.Branch
+			if w.Detached {
+				name = filepath.Base(w.Path)
+			}
+			targets = append(targets, execTarget{name: name, dir: w.Path})
+		}
+	}
+	if filter == "" {
+		return targets, nil
+	}
+	var out []execTarget
+	for _, t := range targets {
+		if matchBranch(filter, t.name) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// workspaceGroupTargets returns the member worktrees of the workspace group
+// the current directory is in. ok is false when repo is not a workspace
+// member or the current directory is not inside one of its groups.
+func workspaceGroupTargets(repo *git.Repo) (targets []execTarget, ok bool, err error) {
+	canonical, err := repo.CanonicalName()
+	if err != nil {
+		return nil, false, nil
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, false, nil
+	}
+	wsName, ws, found := cfg.WorkspaceForRepo(canonical)
+	if !found {
+		return nil, false, nil
+	}
+	current := git.CurrentWorktreeTop()
+	if current == "" {
+		return nil, false, nil
+	}
+	root, err := ws.ResolveWorktreeRoot(wsName)
+	if err != nil {
+		return nil, false, err
+	}
+	group, err := validateGroup(root, "the current worktree", filepath.Dir(current))
+	if err != nil {
+		return nil, false, nil
+	}
+	members, err := cfg.ResolveMembers(ws)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, m := range members {
+		dir := filepath.Join(group, m.Short)
+		if _, statErr := os.Stat(dir); statErr != nil {
+			continue
+		}
+		targets = append(targets, execTarget{name: m.Short, dir: dir})
+	}
+	return targets, true, nil
+}
+
+// execCommand builds the command to run in dir. A single argument is run by
+// sh -c, so pipes and && work when quoted; several arguments run directly.
+func execCommand(argv []string, dir string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if len(argv) == 1 {
+		cmd = exec.Command("sh", "-c", argv[0])
+	} else {
+		cmd = exec.Command(argv[0], argv[1:]...)
+	}
+	cmd.Dir = dir
+	return cmd
+}
+
+// runExec runs argv in every target, at most jobs at a time. Output goes to
+// out: interleaved line by line with a "[name] " prefix, or with grouped set,
+// each target's output in one block once it finishes. Results are returned in
+// target order.
+func runExec(out io.Writer, targets []execTarget, argv []string, jobs int, grouped bool) []execResult {
+	if jobs < 1 {
+		jobs = 1
+	}
+	nameW := 0
+	for _, t := range targets {
+		nameW = max(nameW, len(t.name))
+	}
+
+	var mu sync.Mutex // serializes writes to out
+	results := make([]execResult, len(targets))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, t execTarget) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			cmd := execCommand(argv, t.dir)
+			var buf bytes.Buffer
+			var pw *prefixWriter
+			if grouped {
+				cmd.Stdout, cmd.Stderr = &buf, &buf
+			} else {
+				pw = &prefixWriter{mu: &mu, out: out, prefix: fmt.Sprintf("[%-*s] ", nameW, t.name)}
+				cmd.Stdout, cmd.Stderr = pw, pw
+			}
+			start := time.Now()
+			err := cmd.Run()
+			res := execResult{target: t, err: err, elapsed: time.Since(start)}
+			if err != nil {
+				res.code = -1
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					res.code = exitErr.ExitCode()
+				}
+			}
+			results[i] = res
+
+			if grouped {
+				mu.Lock()
+				fmt.Fprintf(out, "==> %s (%s) <==\n", t.name, t.dir)
+				_, _ = out.Write(buf.Bytes())
+				if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+					fmt.Fprintln(out)
+				}
+				mu.Unlock()
+			} else {
+				pw.Flush()
+			}
+		}(i, t)
+	}
+	wg.Wait()
+	return results
+}
+
+// printExecSummary writes a table of each target's exit status and run time
+// and returns how many failed.
+func printExecSummary(out io.Writer, results []execResult) (failed int) {
+	nameW := len("WORKTREE")
+	for _, r := range results {
+		nameW = max(nameW, len(r.target.name))
+	}
+	fmt.Fprintf(out, "\n%-*s  %-8s  %s\n", nameW, "WORKTREE", "STATUS", "TIME")
+	for _, r := range results {
+		status := "ok"
+		switch {
+		case r.code > 0:
+			status = fmt.Sprintf("exit %d", r.code)
+		case r.err != nil:
+			status = "error"
+		}
+		if r.err != nil {
+			failed++
+		}
+		fmt.Fprintf(out, "%-*s  %-8s  %s\n", nameW, r.target.name, status, r.elapsed.Round(time.Millisecond))
+		if r.code < 0 {
+			fmt.Fprintf(out, "  ! %v\n", r.err)
+		}
+	}
+	return failed
+}
+
+// prefixWriter writes each complete line to out with prefix, holding partial
+// lines back until they are completed or flushed.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	i := bytes.LastIndexByte(w.buf, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+	lines := w.buf[:i+1]
+	w.mu.Lock()
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		if len(line) > 0 {
+			_, _ = io.WriteString(w.out, w.prefix)
+			_, _ = w.out.Write(line)
+		}
+	}
+	w.mu.Unlock()
+	w.buf = append(w.buf[:0], w.buf[i+1:]...)
+	return len(p), nil
+}
+
+// Flush writes any trailing partial line, terminated with a newline.
+func (w *prefixWriter) Flush() {
+	if len(w.buf) == 0 {
+		return
+	}
+	w.buf = append(w.buf, '\n')
+	_, _ = w.Write(nil)
+}
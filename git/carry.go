@@ -0,0 +1,102 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Carry holds uncommitted changes stashed from one worktree so they can be
+// applied in another. The stash is shared by all worktrees of a repo.
+type Carry struct {
+	Source string // worktree the changes were taken from
+	SHA    string // stash commit holding them
+}
+
+// CarrySource returns the worktree containing the current directory, whose
+// changes --carry moves, and the commit it has checked out.
+func CarrySource() (worktree, head string, err error) {
+	worktree = CurrentWorktreeTop()
+	if worktree == "" {
+		return "", "", fmt.Errorf("not inside a git worktree")
+	}
+	head, err = gitOutput(worktree, "rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return "", "", fmt.Errorf("%s has no commits to start from: %w", worktree, err)
+	}
+	return worktree, head, nil
+}
+
+// StartCarry stashes the uncommitted changes of the worktree at source —
+// staged and unstaged, plus untracked files when untracked is set — leaving
+// it clean. ok is false when there was nothing to carry.
+func StartCarry(source string, untracked bool, message string) (c Carry, ok bool, err error) {
+	before, _ := gitOutput(source, "rev-parse", "--verify", "--quiet", "refs/stash")
+	args := []string{"stash", "push", "--quiet", "--message", message}
+	if untracked {
+		args = append(args, "--include-untracked")
+	}
+	if _, err := gitOutput(source, args...); err != nil {
+		return Carry{}, false, fmt.Errorf("failed to stash changes in %s: %w", source, err)
+	}
+	sha, _ := gitOutput(source, "rev-parse", "--verify", "--quiet", "refs/stash")
+	if sha == "" || sha == before {
+		return Carry{}, false, nil
+	}
+	return Carry{Source: source, SHA: sha}, true, nil
+}
+
+// Apply applies the carried changes in the worktree at dest, restoring which
+// changes were staged when it can. On success the stash entry is dropped. When
+// the changes conflict with dest, the conflicted paths are returned and the
+// stash entry is kept (see Ref) so nothing is lost.
+func (c Carry) Apply(dest string) (conflicts []string, err error) {
+	if _, err := gitOutput(dest, "stash", "apply", "--quiet", "--index", c.SHA); err != nil {
+		if conflicts = conflictedPaths(dest); len(conflicts) > 0 {
+			return conflicts, nil
+		}
+		// --index refuses when the staged changes do not apply cleanly; the
+		// changes themselves may still apply unstaged.
+		if _, err := gitOutput(dest, "stash", "apply", "--quiet", c.SHA); err != nil {
+			if conflicts = conflictedPaths(dest); len(conflicts) > 0 {
+				return conflicts, nil
+			}
+			return nil, fmt.Errorf("failed to apply carried changes in %s (kept in %s): %w", dest, c.Ref(), err)
+		}
+	}
+	if ref := c.Ref(); ref != c.SHA {
+		if _, err := gitOutput(dest, "stash", "drop", "--quiet", ref); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// Abort puts the carried changes back into the source worktree, for when the
+// destination could not be created.
+func (c Carry) Abort() error {
+	if _, err := gitOutput(c.Source, "stash", "pop", "--quiet", "--index", c.Ref()); err != nil {
+		return fmt.Errorf("failed to restore changes in %s (kept in %s): %w", c.Source, c.Ref(), err)
+	}
+	return nil
+}
+
+// Ref returns the stash entry (stash@{n}) holding the carried changes, or the
+// stash commit itself once it is no longer in the stash list.
+func (c Carry) Ref() string {
+	out, _ := gitOutput(c.Source, "stash", "list", "--format=%gd %H")
+	for _, line := range strings.Split(out, "\n") {
+		if ref, sha, ok := strings.Cut(line, " "); ok && sha == c.SHA {
+			return ref
+		}
+	}
+	return c.SHA
+}
+
+// conflictedPaths lists the unmerged paths in the worktree at dir.
+func conflictedPaths(dir string) []string {
+	out, err := gitOutput(dir, "diff", "--name-only", "--diff-filter=U")
+	if err != nil || out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
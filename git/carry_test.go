@@ -0,0 +1,107 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCarry(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoDir := filepath.Join(root, "repo")
+	initRepoWithMain(t, repoDir)
+	// stash commits need an identity; gitOutput does not use testGitEnv.
+	testRunGit(t, "git", "-C", repoDir, "config", "user.email", "test@test")
+	testRunGit(t, "git", "-C", repoDir, "config", "user.name", "test")
+	write := func(dir, name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(repoDir, "staged", "s")
+	testRunGit(t, "git", "-C", repoDir, "add", "staged")
+	write(repoDir, "notes", "untracked")
+
+	c, ok, err := StartCarry(repoDir, true, "test carry")
+	if err != nil || !ok {
+		t.Fatalf("StartCarry() = %v, %v", ok, err)
+	}
+	if status, _ := gitOutput(repoDir, "status", "--porcelain"); status != "" {
+		t.Errorf("source not clean after StartCarry():\n%s", status)
+	}
+
+	wt := filepath.Join(root, "wt")
+	if err := AddWorktree(repoDir, NewBranchArgs("feat/x", "main"), wt, AddOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	conflicts, err := c.Apply(wt)
+	if err != nil || len(conflicts) > 0 {
+		t.Fatalf("Apply() = %v, %v", conflicts, err)
+	}
+	status, err := gitOutput(wt, "status", "--porcelain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"A  staged", "?? notes"} {
+		if !strings.Contains(status, line) {
+			t.Errorf("status after Apply() missing %q:\n%s", line, status)
+		}
+	}
+	if list, _ := gitOutput(repoDir, "stash", "list"); list != "" {
+		t.Errorf("stash entry not dropped after Apply():\n%s", list)
+	}
+
+	if _, ok, err := StartCarry(repoDir, true, "nothing"); ok || err != nil {
+		t.Errorf("StartCarry(clean) = %v, %v; want false, nil", ok, err)
+	}
+}
+
+func TestCarryConflict(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoDir := filepath.Join(root, "repo")
+	initRepoWithMain(t, repoDir)
+	testRunGit(t, "git", "-C", repoDir, "config", "user.email", "test@test")
+	testRunGit(t, "git", "-C", repoDir, "config", "user.name", "test")
+	if err := os.WriteFile(filepath.Join(repoDir, "file"), []byte("base\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testRunGit(t, "git", "-C", repoDir, "add", "file")
+	testRunGit(t, "git", "-C", repoDir, "commit", "-m", "base")
+	testRunGit(t, "git", "-C", repoDir, "branch", "other")
+
+	// Diverge the other branch so the carried edit conflicts there.
+	wt := filepath.Join(root, "wt")
+	if err := AddWorktree(repoDir, AddArgs{Branch: "other"}, wt, AddOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wt, "file"), []byte("theirs\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testRunGit(t, "git", "-C", wt, "commit", "-am", "theirs")
+
+	if err := os.WriteFile(filepath.Join(repoDir, "file"), []byte("ours\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, ok, err := StartCarry(repoDir, false, "test carry")
+	if err != nil || !ok {
+		t.Fatalf("StartCarry() = %v, %v", ok, err)
+	}
+	conflicts, err := c.Apply(wt)
+	if err != nil {
+		t.Fatalf("Apply() error: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0] != "file" {
+		t.Errorf("Apply() conflicts = %v, want [file]", conflicts)
+	}
+	if ref := c.Ref(); ref != "stash@{0}" {
+		t.Errorf("Ref() = %q, want the kept stash@{0}", ref)
+	}
+}
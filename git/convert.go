@@ -0,0 +1,201 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ConvertPlan describes how Convert will restructure a regular clone.
+type ConvertPlan struct {
+	Dir          string   // top level of the clone; becomes the bare layout root
+	Branch       string   // branch checked out in the clone
+	WorktreePath string   // where the current checkout moves, Dir/BranchToDir(Branch)
+	Linked       []string // existing linked worktrees to repair afterwards
+}
+
+// PlanConvert inspects the regular clone whose main worktree contains dir and
+// reports what Convert would do, refusing clones it cannot convert safely.
+func PlanConvert(dir string) (ConvertPlan, error) {
+	top, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return ConvertPlan{}, fmt.Errorf("not in a git working tree: %w", err)
+	}
+	top, err = filepath.EvalSymlinks(top)
+	if err != nil {
+		return ConvertPlan{}, err
+	}
+	gitDir := filepath.Join(top, ".git")
+	if fi, err := os.Stat(gitDir); err != nil || !fi.IsDir() {
+		return ConvertPlan{}, fmt.Errorf("%s is not a regular clone (.git is not a directory); run gwt convert from the main worktree", top)
+	}
+	if out, _ := gitOutput(top, "config", "core.worktree"); out != "" {
+		return ConvertPlan{}, fmt.Errorf("core.worktree is set (%s); unset it before converting", out)
+	}
+	for _, marker := range []string{"MERGE_HEAD", "CHERRY_PICK_HEAD", "REVERT_HEAD", "BISECT_LOG", "rebase-merge", "rebase-apply"} {
+		if _, err := os.Stat(filepath.Join(gitDir, marker)); err == nil {
+			return ConvertPlan{}, fmt.Errorf("a merge, rebase, cherry-pick or bisect is in progress; finish it before converting")
+		}
+	}
+	branch, err := gitOutput(top, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		return ConvertPlan{}, fmt.Errorf("HEAD is detached; check out a branch before converting")
+	}
+	if _, err := gitOutput(top, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return ConvertPlan{}, fmt.Errorf("branch %s has no commits yet", branch)
+	}
+
+	plan := ConvertPlan{Dir: top, Branch: branch, WorktreePath: filepath.Join(top, BranchToDir(branch))}
+	if plan.WorktreePath == filepath.Join(top, ".bare") {
+		return ConvertPlan{}, fmt.Errorf("branch %q would collide with .bare", branch)
+	}
+
+	repo := &Repo{Dir: top}
+	infos, err := repo.ListWorktreesFull()
+	if err != nil {
+		return ConvertPlan{}, err
+	}
+	for _, w := range infos {
+		path, err := filepath.EvalSymlinks(w.Path)
+		if err != nil || path == top {
+			continue // the main worktree, or a missing linked one `git worktree prune` will handle
+		}
+		if strings.HasPrefix(path, top+string(filepath.Separator)) {
+			return ConvertPlan{}, fmt.Errorf("linked worktree %s is inside the clone; move it out first (git worktree move)", path)
+		}
+		plan.Linked = append(plan.Linked, path)
+	}
+	return plan, nil
+}
+
+// Convert restructures the regular clone described by plan in place into the
+// layout Clone produces: .git moves to .bare (now a bare repo), a .git file
+// points at it, and the current checkout — uncommitted and untracked files
+// included — becomes the linked worktree at plan.WorktreePath with its index
+// intact. Branches, stashes and hooks live in .bare and are untouched; linked
+// worktrees are repaired to point at the new location. On failure, completed
+// steps are rolled back.
+func Convert(plan ConvertPlan) (retErr error) {
+	top := plan.Dir
+	gitDir := filepath.Join(top, ".git")
+	bareDir := filepath.Join(top, ".bare")
+	if _, err := os.Lstat(bareDir); err == nil {
+		return fmt.Errorf("%s already exists", bareDir)
+	}
+
+	var undo []func() error
+	defer func() {
+		if retErr == nil {
+			return
+		}
+		for i := len(undo) - 1; i >= 0; i-- {
+			if err := undo[i](); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: rollback step failed: %v\n", err)
+			}
+		}
+	}()
+	move := func(from, to string) error {
+		if err := os.Rename(from, to); err != nil {
+			return fmt.Errorf("failed to move %s: %w", from, err)
+		}
+		undo = append(undo, func() error { return os.Rename(to, from) })
+		return nil
+	}
+
+	// Stage the checkout in a temporary directory first: a tracked entry may
+	// share the worktree's name.
+	staging, err := os.MkdirTemp(top, ".gwt-convert-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	undo = append(undo, func() error { return os.Remove(staging) })
+	if err := move(gitDir, bareDir); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(top)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", top, err)
+	}
+	for _, e := range entries {
+		path := filepath.Join(top, e.Name())
+		if path == bareDir || path == staging {
+			continue
+		}
+		if err := move(path, filepath.Join(staging, e.Name())); err != nil {
+			return err
+		}
+	}
+	if err := move(staging, plan.WorktreePath); err != nil {
+		return err
+	}
+
+	// Turn the former main worktree's state into linked-worktree metadata.
+	name := filepath.Base(plan.WorktreePath)
+	adminDir := filepath.Join(bareDir, "worktrees", name)
+	if _, err := os.Lstat(adminDir); err == nil {
+		return fmt.Errorf("worktree metadata %s already exists; run git worktree prune", adminDir)
+	}
+	if err := os.MkdirAll(filepath.Join(adminDir, "logs"), 0o755); err != nil {
+		return fmt.Errorf("failed to create worktree metadata: %w", err)
+	}
+	undo = append(undo, func() error { return os.RemoveAll(adminDir) })
+	files := map[string]string{
+		"HEAD":      "ref: refs/heads/" + plan.Branch + "\n",
+		"commondir": "../..\n",
+		"gitdir":    filepath.Join(plan.WorktreePath, ".git") + "\n",
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(adminDir, file), []byte(content), 0o644); err != nil {
+			return fmt.Errorf("failed to write worktree metadata: %w", err)
+		}
+	}
+	for _, file := range []string{"index", "ORIG_HEAD", filepath.Join("logs", "HEAD")} {
+		if _, err := os.Lstat(filepath.Join(bareDir, file)); err != nil {
+			continue
+		}
+		if err := move(filepath.Join(bareDir, file), filepath.Join(adminDir, file)); err != nil {
+			return err
+		}
+	}
+	wtGitFile := filepath.Join(plan.WorktreePath, ".git")
+	if err := os.WriteFile(wtGitFile, []byte("gitdir: "+adminDir+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write .git file: %w", err)
+	}
+	undo = append(undo, func() error { return os.Remove(wtGitFile) })
+
+	if _, err := gitOutput(bareDir, "config", "core.bare", "true"); err != nil {
+		return err
+	}
+	undo = append(undo, func() error {
+		_, err := gitOutput(bareDir, "config", "core.bare", "false")
+		return err
+	})
+	topGitFile := filepath.Join(top, ".git")
+	if err := os.WriteFile(topGitFile, []byte("gitdir: ./.bare\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write .git file: %w", err)
+	}
+	undo = append(undo, func() error { return os.Remove(topGitFile) })
+
+	// Nothing below is rolled back: the conversion itself is complete.
+	undo = nil
+
+	if len(plan.Linked) > 0 {
+		// repair reports each fixed link as "broken"; only show it on failure.
+		if _, err := gitOutput(top, append([]string{"worktree", "repair"}, plan.Linked...)...); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	}
+	// Renames change ctime, which makes every index entry look stale; refresh
+	// so the first `git status` is not a full rehash.
+	_ = exec.Command("git", "-C", plan.WorktreePath, "update-index", "-q", "--refresh").Run()
+
+	if _, err := gitOutput(top, "remote", "get-url", "origin"); err == nil {
+		repo := &Repo{Dir: top, IsBare: true}
+		if err := repo.ConfigureFetch(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tmp, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := filepath.Join(tmp, "source")
+	initRepoWithMain(t, source)
+	clone := filepath.Join(tmp, "clone")
+	testRunGit(t, "git", "clone", "--quiet", source, clone)
+
+	run := func(args ...string) { testRunGit(t, "git", append([]string{"-C", clone}, args...)...) }
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(clone, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("checkout", "--quiet", "-b", "feat/x")
+	write("stashed", "s")
+	run("add", "stashed")
+	run("stash", "--quiet")
+	write("staged", "a")
+	run("add", "staged")
+	write("README", "modified")
+	write("untracked", "u")
+	linked := filepath.Join(tmp, "linked")
+	run("worktree", "add", "--quiet", linked, "main")
+
+	plan, err := PlanConvert(clone)
+	if err != nil {
+		t.Fatalf("PlanConvert() error: %v", err)
+	}
+	want := ConvertPlan{Dir: clone, Branch: "feat/x", WorktreePath: filepath.Join(clone, "feat-x"), Linked: []string{linked}}
+	if plan.Dir != want.Dir || plan.Branch != want.Branch || plan.WorktreePath != want.WorktreePath ||
+		len(plan.Linked) != 1 || plan.Linked[0] != linked {
+		t.Fatalf("PlanConvert() = %+v, want %+v", plan, want)
+	}
+	if err := Convert(plan); err != nil {
+		t.Fatalf("Convert() error: %v", err)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(clone, ".git")); err != nil || string(data) != "gitdir: ./.bare\n" {
+		t.Errorf(".git file = %q, %v", data, err)
+	}
+	if out, _ := gitOutput(clone, "rev-parse", "--is-bare-repository"); out != "true" {
+		t.Errorf("converted repo is not bare: %q", out)
+	}
+	wt := plan.WorktreePath
+	if out, _ := gitOutput(wt, "symbolic-ref", "--short", "HEAD"); out != "feat/x" {
+		t.Errorf("worktree HEAD = %q, want feat/x", out)
+	}
+	status, err := gitOutput(wt, "status", "--porcelain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"M README", "A  staged", "?? untracked"} {
+		if !strings.Contains(status, line) {
+			t.Errorf("status missing %q:\n%s", line, status)
+		}
+	}
+	if out, _ := gitOutput(wt, "stash", "list"); !strings.Contains(out, "stash@{0}") {
+		t.Errorf("stash lost: %q", out)
+	}
+	if out, err := gitOutput(linked, "status", "--porcelain"); err != nil || out != "" {
+		t.Errorf("linked worktree status = %q, %v; want clean", out, err)
+	}
+	if out, _ := gitOutput(clone, "config", "remote.origin.fetch"); out != "+refs/heads/*:refs/remotes/origin/*" {
+		t.Errorf("remote.origin.fetch = %q", out)
+	}
+}
+
+func TestPlanConvertRefuses(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, dir string)
+		wantErr string
+	}{
+		{"detached HEAD", func(t *testing.T, dir string) {
+			testRunGit(t, "git", "-C", dir, "checkout", "--quiet", "--detach")
+		}, "detached"},
+		{"linked worktree inside", func(t *testing.T, dir string) {
+			testRunGit(t, "git", "-C", dir, "worktree", "add", "--quiet", "-b", "inner", filepath.Join(dir, "wt", "inner"))
+		}, "inside the clone"},
+		{"merge in progress", func(t *testing.T, dir string) {
+			if err := os.WriteFile(filepath.Join(dir, ".git", "MERGE_HEAD"), []byte("x"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}, "in progress"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "repo")
+			initRepoWithMain(t, dir)
+			tt.setup(t, dir)
+			if _, err := PlanConvert(dir); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("PlanConvert() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("linked worktree", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "repo")
+		initRepoWithMain(t, dir)
+		wt := filepath.Join(t.TempDir(), "wt")
+		testRunGit(t, "git", "-C", dir, "worktree", "add", "--quiet", "-b", "side", wt)
+		if _, err := PlanConvert(wt); err == nil || !strings.Contains(err.Error(), "main worktree") {
+			t.Errorf("PlanConvert(linked) error = %v, want main worktree hint", err)
+		}
+	})
+}
@@ -0,0 +1,272 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// The shell wrapper printed by gwt shell-init runs every gwt command with
+// GWT_DIRECTIVE_FILE naming an empty file and, once gwt exits, applies each
+// line written to it to the parent shell, in order:
+//
+//	cd <dir>
+//	export <NAME>=<value>
+//	unset <NAME>
+//	source <file>
+//
+// Wrappers from before directives existed set GWT_CD_FILE instead, to a file
+// that receives only the path to cd to.
+const (
+	directiveFileVar = "GWT_DIRECTIVE_FILE"
+	legacyCdFileVar  = "GWT_CD_FILE"
+	shellVar         = "GWT_SHELL" // set by the fish, nu and pwsh wrappers
+	envVarsVar       = "GWT_ENV_VARS"
+	savedVarPrefix   = "GWT_ENV_SAVED_" // + NAME: the value NAME had before a worktree changed it
+)
+
+// EnvFile is the per-worktree file whose exports, unsets and sources are
+// applied to the shell whenever gwt sends it into that worktree.
+const EnvFile = ".gwt-env"
+
+// Directive is one instruction to the shell wrapper.
+type Directive struct {
+	Op  string // "cd", "export", "unset" or "source"
+	Arg string // the directory, NAME=value, NAME or file
+}
+
+func Cd(dir string) Directive             { return Directive{"cd", dir} }
+func Export(name, value string) Directive { return Directive{"export", name + "=" + value} }
+func Unset(name string) Directive         { return Directive{"unset", name} }
+func Source(file string) Directive        { return Directive{"source", file} }
+
+func (d Directive) String() string {
+	return d.Op + " " + d.Arg
+}
+
+// WriteDirectives appends ds to the shell wrapper's directive file. It does
+// nothing outside the wrapper; under an old wrapper only the last cd is
+// passed on. Directives whose argument is empty or spans lines are dropped.
+func WriteDirectives(ds ...Directive) {
+	ds = slices.DeleteFunc(slices.Clone(ds), func(d Directive) bool {
+		return d.Arg == "" || strings.ContainsAny(d.Arg, "\r\n")
+	})
+	if file := os.Getenv(directiveFileVar); file != "" {
+		var b strings.Builder
+		for _, d := range ds {
+			b.WriteString(d.String() + "\n")
+		}
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return
+		}
+		_, _ = f.WriteString(b.String())
+		_ = f.Close()
+		return
+	}
+	if cdFile := os.Getenv(legacyCdFileVar); cdFile != "" {
+		for i := len(ds) - 1; i >= 0; i-- {
+			if ds[i].Op == "cd" {
+				_ = os.WriteFile(cdFile, []byte(ds[i].Arg), 0o644)
+				return
+			}
+		}
+	}
+}
+
+// WriteCd tells the shell wrapper to cd to dir and take on the environment
+// of worktree (see EnvDirectives). dir is usually worktree or a directory
+// inside it.
+func WriteCd(dir, worktree string) {
+	ds, err := EnvDirectives(worktree)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	WriteDirectives(append([]Directive{Cd(dir)}, ds...)...)
+}
+
+// EnvDirectives returns what gives the shell the environment of worktree:
+// restores of the variables the last worktree's EnvFile changed and this one
+// does not, saves of the values this one is about to change, this
+// worktree's EnvFile, and an export of GWT_ENV_VARS listing the names it
+// exports or unsets. A variable is restored to the value it had before any
+// worktree changed it, or unset when it had none. It returns nothing outside
+// a directive-aware wrapper. On error the restores are still returned.
+func EnvDirectives(worktree string) ([]Directive, error) {
+	if os.Getenv(directiveFileVar) == "" {
+		return nil, nil
+	}
+	env, err := WorktreeEnv(worktree)
+	var changed []string
+	for _, d := range env {
+		if d.Op == "export" || d.Op == "unset" {
+			name, _, _ := strings.Cut(d.Arg, "=")
+			if !slices.Contains(changed, name) {
+				changed = append(changed, name)
+			}
+		}
+	}
+	var ds []Directive
+	previous := os.Getenv(envVarsVar)
+	managed := strings.Split(previous, ",")
+	for _, name := range managed {
+		if name == "" || slices.Contains(changed, name) {
+			continue
+		}
+		if value, ok := os.LookupEnv(savedVarPrefix + name); ok {
+			ds = append(ds, Export(name, value), Unset(savedVarPrefix+name))
+		} else {
+			ds = append(ds, Unset(name))
+		}
+	}
+	for _, name := range changed {
+		// A name the last worktree changed already has its original saved.
+		if slices.Contains(managed, name) {
+			continue
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			ds = append(ds, Export(savedVarPrefix+name, value))
+		}
+	}
+	ds = append(ds, env...)
+	switch {
+	case len(changed) > 0:
+		ds = append(ds, Export(envVarsVar, strings.Join(changed, ",")))
+	case previous != "":
+		ds = append(ds, Unset(envVarsVar))
+	}
+	return ds, err
+}
+
+// originalValue returns the value of the variable name as the shell had it
+// before any worktree's EnvFile changed it.
+func originalValue(name string) string {
+	if slices.Contains(strings.Split(os.Getenv(envVarsVar), ","), name) {
+		return os.Getenv(savedVarPrefix + name)
+	}
+	return os.Getenv(name)
+}
+
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// WorktreeEnv parses the EnvFile at the top of worktree, which is nil when
+// there is none. Lines are blank, # comments, NAME=value (optionally
+// prefixed with export and with the value quoted), unset NAME, or
+// source FILE with FILE relative to the worktree. Outside single quotes,
+// $NAME and ${NAME} are expanded: $GWT_BRANCH, $GWT_WORKTREE and
+// $GWT_WORKTREE_NAME describe the worktree, names set earlier in the file
+// take that value, and anything else takes the value the shell had before
+// any worktree changed it, so PATH=$GWT_WORKTREE/bin:$PATH does not pile up.
+//
+// An EnvFile tracked by git is refused: it would let any repository run code
+// in the shell. Keep it untracked, e.g. written by the post-checkout hook or
+// copied with gwt init -c.
+func WorktreeEnv(worktree string) ([]Directive, error) {
+	path := filepath.Join(worktree, EnvFile)
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	tracked, err := gitOutput(worktree, "ls-files", "--", EnvFile)
+	if err != nil {
+		return nil, fmt.Errorf("ignoring %s: cannot tell whether it is tracked by git: %w", path, err)
+	}
+	if tracked != "" {
+		return nil, fmt.Errorf("ignoring %s: it is tracked by git; keep it untracked so only you decide what it runs", path)
+	}
+
+	branch, _ := gitOutput(worktree, "branch", "--show-current")
+	vars := map[string]string{
+		"GWT_BRANCH":        branch,
+		"GWT_WORKTREE":      worktree,
+		"GWT_WORKTREE_NAME": filepath.Base(worktree),
+	}
+	expand := func(s string) string {
+		if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+			return s[1 : len(s)-1]
+		}
+		return os.Expand(unquote(s), func(name string) string {
+			if value, ok := vars[name]; ok {
+				return value
+			}
+			return originalValue(name)
+		})
+	}
+
+	var ds []Directive
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		bad := func(why string) error { return fmt.Errorf("%s:%d: %s", path, n, why) }
+		word, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		switch word {
+		case "unset":
+			if !envNameRe.MatchString(rest) {
+				return nil, bad(fmt.Sprintf("invalid variable name %q", rest))
+			}
+			vars[rest] = ""
+			ds = append(ds, Unset(rest))
+		case "source":
+			if rest == "" {
+				return nil, bad("source needs a file")
+			}
+			file := expand(rest)
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(worktree, file)
+			}
+			ds = append(ds, Source(shellVariant(file)))
+		default:
+			if word == "export" {
+				line = rest
+			}
+			name, value, ok := strings.Cut(line, "=")
+			if !ok || !envNameRe.MatchString(name) {
+				return nil, bad(fmt.Sprintf("expected NAME=value, unset NAME or source FILE, got %q", line))
+			}
+			vars[name] = expand(value)
+			ds = append(ds, Export(name, vars[name]))
+		}
+	}
+	return ds, scanner.Err()
+}
+
+// unquote strips one pair of matching single or double quotes around s.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// shellVariant returns the flavor of file for the wrapper's shell when one
+// sits next to it, e.g. .venv/bin/activate.fish under fish or Activate.ps1
+// under PowerShell, and file itself otherwise.
+func shellVariant(file string) string {
+	ext := map[string]string{"fish": ".fish", "nu": ".nu", "pwsh": ".ps1"}[os.Getenv(shellVar)]
+	if ext == "" {
+		return file
+	}
+	entries, err := os.ReadDir(filepath.Dir(file))
+	if err != nil {
+		return file
+	}
+	want := filepath.Base(file) + ext
+	for _, e := range entries {
+		if strings.EqualFold(e.Name(), want) {
+			return filepath.Join(filepath.Dir(file), e.Name())
+		}
+	}
+	return file
+}
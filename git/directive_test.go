@@ -0,0 +1,196 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteDirectives(t *testing.T) {
+	t.Run("appends directives when env var is set", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "directives")
+		t.Setenv("GWT_DIRECTIVE_FILE", file)
+		t.Setenv("GWT_CD_FILE", "")
+
+		WriteDirectives(Cd("/some/path"), Export("PORT", "3001"))
+		WriteDirectives(Unset("OLD"), Export("BAD", "two\nlines"), Cd(""), Source("/venv/bin/activate"))
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read directive file: %v", err)
+		}
+		want := "cd /some/path\nexport PORT=3001\nunset OLD\nsource /venv/bin/activate\n"
+		if string(data) != want {
+			t.Errorf("directive file = %q, want %q", string(data), want)
+		}
+	})
+
+	t.Run("writes the last cd for old wrappers", func(t *testing.T) {
+		cdFile := filepath.Join(t.TempDir(), "cd-target")
+		t.Setenv("GWT_DIRECTIVE_FILE", "")
+		t.Setenv("GWT_CD_FILE", cdFile)
+
+		WriteDirectives(Cd("/first"), Export("PORT", "3001"), Cd("/some/path"))
+
+		data, err := os.ReadFile(cdFile)
+		if err != nil {
+			t.Fatalf("failed to read cd file: %v", err)
+		}
+		if string(data) != "/some/path" {
+			t.Errorf("cd file = %q, want %q", string(data), "/some/path")
+		}
+	})
+
+	t.Run("no-op when path is empty", func(t *testing.T) {
+		cdFile := filepath.Join(t.TempDir(), "cd-target")
+		t.Setenv("GWT_DIRECTIVE_FILE", "")
+		t.Setenv("GWT_CD_FILE", cdFile)
+
+		WriteDirectives(Cd(""))
+
+		if _, err := os.Stat(cdFile); err == nil {
+			t.Error("cd file should not exist when path is empty")
+		}
+	})
+
+	t.Run("no-op outside the wrapper", func(t *testing.T) {
+		t.Setenv("GWT_DIRECTIVE_FILE", "")
+		t.Setenv("GWT_CD_FILE", "")
+
+		// Should not panic or create any file
+		WriteDirectives(Cd("/some/path"))
+	})
+}
+
+func TestWorktreeEnv(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "repo")
+	initRepoWithMain(t, dir)
+	t.Setenv("GWT_SHELL", "")
+	if ds, err := WorktreeEnv(dir); err != nil || ds != nil {
+		t.Fatalf("WorktreeEnv() without %s = %v, %v; want nil, nil", EnvFile, ds, err)
+	}
+
+	venv := filepath.Join(dir, ".venv", "bin")
+	if err := os.MkdirAll(venv, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"activate", "activate.fish", "Activate.ps1"} {
+		if err := os.WriteFile(filepath.Join(venv, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env := `# per-worktree environment
+COMPOSE_PROJECT_NAME=app-${GWT_WORKTREE_NAME}
+export PORT="3001"
+BRANCH="$GWT_BRANCH"
+LITERAL='$HOME/x'
+PATH=${GWT_WORKTREE}/bin:$PATH
+BIN=$PATH
+unset DEBUG
+source .venv/bin/activate
+`
+	if err := os.WriteFile(filepath.Join(dir, EnvFile), []byte(env), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// PATH as the last worktree left it; $PATH expands to the saved original.
+	t.Setenv("GWT_ENV_VARS", "PATH")
+	t.Setenv("GWT_ENV_SAVED_PATH", "/usr/bin")
+	t.Setenv("PATH", "/elsewhere/bin:/usr/bin")
+	ds, err := WorktreeEnv(dir)
+	if err != nil {
+		t.Fatalf("WorktreeEnv() error: %v", err)
+	}
+	want := []Directive{
+		Export("COMPOSE_PROJECT_NAME", "app-repo"),
+		Export("PORT", "3001"),
+		Export("BRANCH", "main"),
+		Export("LITERAL", "$HOME/x"),
+		Export("PATH", dir+"/bin:/usr/bin"),
+		Export("BIN", dir+"/bin:/usr/bin"),
+		Unset("DEBUG"),
+		Source(filepath.Join(venv, "activate")),
+	}
+	if !reflect.DeepEqual(ds, want) {
+		t.Errorf("WorktreeEnv() = %v, want %v", ds, want)
+	}
+
+	for shell, file := range map[string]string{"fish": "activate.fish", "pwsh": "Activate.ps1", "nu": "activate"} {
+		t.Setenv("GWT_SHELL", shell)
+		ds, _ := WorktreeEnv(dir)
+		if got := ds[len(ds)-1].Arg; got != filepath.Join(venv, file) {
+			t.Errorf("source under %s = %q, want %s", shell, got, file)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, EnvFile), []byte("PORT 3001\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WorktreeEnv(dir); err == nil || !strings.Contains(err.Error(), ":1:") {
+		t.Errorf("WorktreeEnv() with a bad line: err = %v, want one naming line 1", err)
+	}
+
+	// Outside a repository git cannot say whether it is tracked.
+	loose := t.TempDir()
+	if err := os.WriteFile(filepath.Join(loose, EnvFile), []byte("PORT=3001\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WorktreeEnv(loose); err == nil || !strings.Contains(err.Error(), "cannot tell") {
+		t.Errorf("WorktreeEnv() when git ls-files fails: err = %v, want refusal", err)
+	}
+
+	testRunGit(t, "git", "-C", dir, "add", "-f", EnvFile)
+	if _, err := WorktreeEnv(dir); err == nil || !strings.Contains(err.Error(), "tracked") {
+		t.Errorf("WorktreeEnv() with a tracked %s: err = %v, want refusal", EnvFile, err)
+	}
+}
+
+func TestEnvDirectives(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "repo")
+	initRepoWithMain(t, dir)
+	if err := os.WriteFile(filepath.Join(dir, EnvFile), []byte("PORT=3001\nHOST=localhost\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	other := t.TempDir()
+
+	t.Setenv("GWT_DIRECTIVE_FILE", "")
+	if ds, _ := EnvDirectives(dir); ds != nil {
+		t.Errorf("EnvDirectives() outside the wrapper = %v, want nil", ds)
+	}
+
+	t.Setenv("GWT_DIRECTIVE_FILE", filepath.Join(t.TempDir(), "directives"))
+	tests := []struct {
+		name     string
+		previous string
+		env      map[string]string
+		worktree string
+		want     []Directive
+	}{
+		{"enter", "", nil, dir, []Directive{Export("PORT", "3001"), Export("HOST", "localhost"), Export("GWT_ENV_VARS", "PORT,HOST")}},
+		{"enter saves the user's values", "", map[string]string{"PORT": "8080"}, dir, []Directive{Export("GWT_ENV_SAVED_PORT", "8080"), Export("PORT", "3001"), Export("HOST", "localhost"), Export("GWT_ENV_VARS", "PORT,HOST")}},
+		{"switch", "PORT,DB", map[string]string{"GWT_ENV_SAVED_PORT": "8080"}, dir, []Directive{Unset("DB"), Export("PORT", "3001"), Export("HOST", "localhost"), Export("GWT_ENV_VARS", "PORT,HOST")}},
+		{"leave", "PORT,HOST", nil, other, []Directive{Unset("PORT"), Unset("HOST"), Unset("GWT_ENV_VARS")}},
+		{"leave restores the user's values", "PORT,HOST", map[string]string{"GWT_ENV_SAVED_PORT": "8080"}, other, []Directive{Export("PORT", "8080"), Unset("GWT_ENV_SAVED_PORT"), Unset("HOST"), Unset("GWT_ENV_VARS")}},
+		{"neither", "", nil, other, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"PORT", "HOST", "DB", "GWT_ENV_SAVED_PORT"} {
+				t.Setenv(name, "")
+				_ = os.Unsetenv(name)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			t.Setenv("GWT_ENV_VARS", tt.previous)
+			ds, err := EnvDirectives(tt.worktree)
+			if err != nil {
+				t.Fatalf("EnvDirectives() error: %v", err)
+			}
+			if !reflect.DeepEqual(ds, tt.want) {
+				t.Errorf("EnvDirectives() = %v, want %v", ds, tt.want)
+			}
+		})
+	}
+}
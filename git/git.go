@@ -7,8 +7,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/nicwestvold/gwt/disk"
 )
@@ -94,21 +98,27 @@ func (r *Repo) Passthrough(args []string) error {
 	return cmd.Run()
 }
 
+// RemoveOptions controls what removing a worktree cleans up besides the
+// worktree itself.
+type RemoveOptions struct {
+	KeepBranch  bool // leave the local branch in place
+	Remote      bool // also delete the branch's remote branch (DeleteRemoteBranch)
+	ForceRemote bool // delete the remote branch even when it diverged from the local one
+	Force       bool // remove despite work loss
+}
+
 // RemoveResult reports the outcome of removing a single worktree.
 type RemoveResult struct {
 	RepoDir      string
 	WorktreePath string
-	Branch       string      // "" if detached
-	Freed        disk.Result // on-disk space reclaimed
+	Branch       string         // "" if detached
+	Freed        disk.Result    // on-disk space reclaimed
+	Remote       RemoteDeletion // outcome of deleting the remote branch, with opts.Remote
 }
 
-// Remove removes a worktree. If no positional path argument is provided,
-// it auto-detects the current worktree directory. Returns the repo dir
-// (for cd-back) and the removed worktree path (for cleanup).
-func (r *Repo) Remove(args []string, keepBranch bool) (RemoveResult, error) {
-	// Separate flags from positional args, respecting "--" separator.
-	var flags []string
-	var positional []string
+// splitRemoveArgs separates flags from positional args, respecting the "--"
+// separator (kept with the flags, since git worktree remove accepts it).
+func splitRemoveArgs(args []string) (flags, positional []string) {
 	pastSeparator := false
 	for _, a := range args {
 		if !pastSeparator && a == "--" {
@@ -122,8 +132,14 @@ func (r *Repo) Remove(args []string, keepBranch bool) (RemoveResult, error) {
 			positional = append(positional, a)
 		}
 	}
+	return flags, positional
+}
 
-	var worktreePath string
+// removalTarget resolves the worktree a remove-style command acts on: the
+// first positional argument, or the worktree containing the current directory
+// when there is none. It refuses the main working tree and returns the
+// worktree's symlink-resolved path and branch ("" if detached).
+func (r *Repo) removalTarget(positional []string) (worktreePath, branch string, err error) {
 	if len(positional) == 0 {
 		// Auto-detect current worktree from the user's working directory.
 		var buf, stderr bytes.Buffer
@@ -131,15 +147,14 @@ func (r *Repo) Remove(args []string, keepBranch bool) (RemoveResult, error) {
 		cmd.Stdout = &buf
 		cmd.Stderr = &stderr
 		if err := cmd.Run(); err != nil {
-			return RemoveResult{}, fmt.Errorf("not inside a worktree: %w (%s)", err, strings.TrimSpace(stderr.String()))
+			return "", "", fmt.Errorf("not inside a worktree: %w (%s)", err, strings.TrimSpace(stderr.String()))
 		}
 		worktreePath = strings.TrimSpace(buf.String())
 	} else {
 		// Resolve the provided path to absolute.
-		var err error
 		worktreePath, err = filepath.Abs(positional[0])
 		if err != nil {
-			return RemoveResult{}, fmt.Errorf("failed to resolve path: %w", err)
+			return "", "", fmt.Errorf("failed to resolve path: %w", err)
 		}
 	}
 
@@ -155,11 +170,10 @@ func (r *Repo) Remove(args []string, keepBranch bool) (RemoveResult, error) {
 		resolvedDir = resolved
 	}
 	if filepath.Clean(worktreePath) == filepath.Clean(resolvedDir) {
-		return RemoveResult{}, fmt.Errorf("refusing to remove the main working tree: %s", worktreePath)
+		return "", "", fmt.Errorf("refusing to remove the main working tree: %s", worktreePath)
 	}
 
 	// Detect the branch checked out in the worktree before removal.
-	var branch string
 	{
 		var buf bytes.Buffer
 		bc := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
@@ -173,6 +187,32 @@ func (r *Repo) Remove(args []string, keepBranch bool) (RemoveResult, error) {
 		}
 	}
 
+	return worktreePath, branch, nil
+}
+
+// Remove removes a worktree. If no positional path argument is provided,
+// it auto-detects the current worktree directory. Unless opts.Force or
+// -f/--force is among args, a worktree holding work that would be lost is
+// refused with a *WorkLossError. Returns the repo dir (for cd-back) and the removed worktree
+// path (for cleanup).
+func (r *Repo) Remove(args []string, opts RemoveOptions) (RemoveResult, error) {
+	flags, positional := splitRemoveArgs(args)
+	worktreePath, branch, err := r.removalTarget(positional)
+	if err != nil {
+		return RemoveResult{}, err
+	}
+
+	for _, f := range flags {
+		if f == "-f" || f == "--force" {
+			opts.Force = true
+		}
+	}
+	if !opts.Force {
+		if err := CheckRemovable(worktreePath); err != nil {
+			return RemoveResult{}, err
+		}
+	}
+
 	freed, _ := disk.Size(worktreePath) // best-effort; never blocks removal
 
 	gitArgs := []string{"worktree", "remove"}
@@ -188,8 +228,13 @@ func (r *Repo) Remove(args []string, keepBranch bool) (RemoveResult, error) {
 		return RemoveResult{}, fmt.Errorf("git worktree remove failed: %w", err)
 	}
 
+	var remote RemoteDeletion
+	if opts.Remote && branch != "" {
+		remote = DeleteRemoteBranch(r.Dir, branch, opts.ForceRemote)
+	}
+
 	// Best-effort branch deletion (non-force).
-	if !keepBranch && branch != "" {
+	if !opts.KeepBranch && branch != "" {
 		delCmd := exec.Command("git", "branch", "-d", branch)
 		delCmd.Dir = r.Dir
 		delCmd.Stdout = os.Stdout
@@ -204,6 +249,7 @@ func (r *Repo) Remove(args []string, keepBranch bool) (RemoveResult, error) {
 		WorktreePath: worktreePath,
 		Branch:       branch,
 		Freed:        freed,
+		Remote:       remote,
 	}, nil
 }
 
@@ -224,49 +270,187 @@ func CleanEmptyParents(dir, stopAt string) {
 	}
 }
 
+// AddOptions adjusts how a worktree is created.
+type AddOptions struct {
+	Sparse  []string // cone-mode directories to check out (see SparseCheckout); nil for all
+	Offline bool     // never fetch, even when the branch or start point is missing
+}
+
 // Add creates a worktree. baseDir is the parent directory where the
 // worktree subdirectory will be created.
-func (r *Repo) Add(args []string, baseDir string) (string, error) {
-	gitArgs, worktreePath, err := buildAddArgs(args, baseDir)
+func (r *Repo) Add(args []string, baseDir string, opts AddOptions) (string, error) {
+	a, err := ParseAddArgs(args)
 	if err != nil {
 		return "", err
 	}
+	worktreePath := filepath.Join(baseDir, BranchToDir(a.Branch))
+	if err := AddWorktree(r.Dir, a, worktreePath, opts); err != nil {
+		return "", err
+	}
+	return worktreePath, nil
+}
 
-	fullArgs := append([]string{"worktree", "add"}, gitArgs...)
+// AddWorktree runs `git worktree add` in repoDir for a at worktreePath. If the
+// branch or start point has not been fetched, it fetches just that branch from
+// the remote it names (the whole remote if that fails) and retries; a branch
+// found that way is created tracking its remote branch. opts.Offline skips
+// the fetch.
+func AddWorktree(repoDir string, a AddArgs, worktreePath string, opts AddOptions) error {
+	// Only a worktree this call creates can have had its setup fail here;
+	// git refuses to add over an existing one.
+	_, statErr := os.Stat(worktreePath)
+	isNew := os.IsNotExist(statErr)
+	build := func(a AddArgs) []string {
+		args := append([]string{"-C", repoDir, "worktree", "add"}, a.Build(worktreePath)...)
+		if len(opts.Sparse) > 0 {
+			args = slices.Insert(args, 4, "--no-checkout")
+		}
+		return args
+	}
 
-	// First attempt: capture stderr to detect "invalid reference"
+	// First attempt: capture stderr to detect a missing ref
 	var stderrBuf bytes.Buffer
-	cmd := exec.Command("git", fullArgs...)
-	cmd.Dir = r.Dir
+	cmd := exec.Command("git", build(a)...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = &stderrBuf
 	cmd.Stdin = os.Stdin
 	if err := cmd.Run(); err != nil {
-		if strings.Contains(stderrBuf.String(), "invalid reference:") {
-			// Branch not fetched yet — fetch and retry
-			fetchCmd := exec.Command("git", "fetch", "origin")
-			fetchCmd.Dir = r.Dir
-			fetchCmd.Stdout = os.Stdout
-			fetchCmd.Stderr = os.Stderr
-			if fetchErr := fetchCmd.Run(); fetchErr != nil {
-				return "", fmt.Errorf("git fetch failed: %w", fetchErr)
+		// Other error, or offline — flush captured stderr so user sees it
+		if !missingRef(stderrBuf.String()) || opts.Offline {
+			_, _ = os.Stderr.Write(stderrBuf.Bytes())
+			if isNew {
+				markSetupFailed(worktreePath, err)
 			}
-			// Retry with normal stderr passthrough
-			retryCmd := exec.Command("git", fullArgs...)
-			retryCmd.Dir = r.Dir
-			retryCmd.Stdout = os.Stdout
-			retryCmd.Stderr = os.Stderr
-			retryCmd.Stdin = os.Stdin
-			if retryErr := retryCmd.Run(); retryErr != nil {
-				return "", fmt.Errorf("git worktree add failed: %w", retryErr)
+			if opts.Offline {
+				return fmt.Errorf("git worktree add failed: %w (offline, so nothing was fetched)", err)
 			}
-			return worktreePath, nil
+			return fmt.Errorf("git worktree add failed: %w", err)
+		}
+
+		remote, branch := splitRemoteRef(repoDir, a.startRef())
+		if err := fetchBranch(repoDir, remote, branch); err != nil {
+			return err
+		}
+		if a.BranchFlag == "" && !refExists(repoDir, "refs/heads/"+a.Branch) &&
+			refExists(repoDir, "refs/remotes/"+remote+"/"+branch) {
+			a = AddArgs{
+				Flags:      append(append([]string{}, a.Flags...), "--track", "-b", a.Branch),
+				BranchFlag: "-b",
+				Branch:     a.Branch,
+				Extra:      []string{remote + "/" + branch},
+			}
+		}
+
+		// Retry with normal stderr passthrough
+		retryCmd := exec.Command("git", build(a)...)
+		retryCmd.Stdout = os.Stdout
+		retryCmd.Stderr = os.Stderr
+		retryCmd.Stdin = os.Stdin
+		if retryErr := retryCmd.Run(); retryErr != nil {
+			if isNew {
+				markSetupFailed(worktreePath, retryErr)
+			}
+			return fmt.Errorf("git worktree add failed: %w", retryErr)
 		}
-		// Other error — flush captured stderr so user sees it
-		_, _ = os.Stderr.Write(stderrBuf.Bytes())
-		return "", fmt.Errorf("git worktree add failed: %w", err)
 	}
-	return worktreePath, nil
+	if len(opts.Sparse) > 0 {
+		return SparseCheckout(worktreePath, opts.Sparse)
+	}
+	return nil
+}
+
+// fetchBranch fetches only branch from remote into its remote-tracking ref,
+// falling back to fetching the whole remote when that fails (the ref may be
+// a tag or commit rather than a branch).
+func fetchBranch(repoDir, remote, branch string) error {
+	if branch != "" {
+		cmd := exec.Command("git", "-C", repoDir, "fetch", remote, remoteRefspec(remote, branch))
+		cmd.Stdout = os.Stdout
+		if cmd.Run() == nil {
+			return nil
+		}
+	}
+	cmd := exec.Command("git", "-C", repoDir, "fetch", remote)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git fetch failed: %w", err)
+	}
+	return nil
+}
+
+// refExists reports whether the full ref name resolves in repoDir.
+func refExists(repoDir, ref string) bool {
+	return exec.Command("git", "-C", repoDir, "rev-parse", "--verify", "--quiet", ref).Run() == nil
+}
+
+// nullSHA is the all-zero object name git passes as the previous HEAD to the
+// post-checkout hook of a newly created worktree.
+const nullSHA = "0000000000000000000000000000000000000000"
+
+// SparseCheckout restricts the worktree at worktreePath, which must have been
+// added with --no-checkout, to the cone-mode directories dirs, then checks it
+// out. The hook is suppressed during that checkout and run afterwards with the
+// arguments `git worktree add` would have given it, so the post-checkout hook
+// still sees a new worktree.
+func SparseCheckout(worktreePath string, dirs []string) error {
+	setArgs := append([]string{"-C", worktreePath, "sparse-checkout", "set", "--cone", "--"}, dirs...)
+	var stderr bytes.Buffer
+	setCmd := exec.Command("git", setArgs...)
+	setCmd.Stderr = &stderr
+	if err := setCmd.Run(); err != nil {
+		return fmt.Errorf("git sparse-checkout set failed: %w (%s)", err, strings.TrimSpace(stderr.String()))
+	}
+
+	checkoutCmd := exec.Command("git", "-C", worktreePath, "-c", "core.hooksPath="+os.DevNull, "checkout")
+	checkoutCmd.Stdout = os.Stdout
+	checkoutCmd.Stderr = os.Stderr
+	if err := checkoutCmd.Run(); err != nil {
+		return fmt.Errorf("git checkout failed: %w", err)
+	}
+
+	return RunPostCheckout(worktreePath)
+}
+
+// RunPostCheckout runs the post-checkout hook in the worktree at
+// worktreePath with the arguments `git worktree add` gives it, so it sets the
+// worktree up as if new. A failure is recorded for gwt prompt; success clears
+// an earlier one.
+func RunPostCheckout(worktreePath string) error {
+	head, err := gitOutput(worktreePath, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	hookCmd := exec.Command("git", "-C", worktreePath, "hook", "run", "--ignore-missing", "post-checkout", "--", nullSHA, head, "1")
+	hookCmd.Stdout = os.Stdout
+	hookCmd.Stderr = os.Stderr
+	hookCmd.Stdin = os.Stdin
+	if err := hookCmd.Run(); err != nil {
+		markSetupFailed(worktreePath, err)
+		return fmt.Errorf("post-checkout hook failed: %w", err)
+	}
+	clearSetupFailed(worktreePath)
+	return nil
+}
+
+// IgnoredFiles lists the untracked files in the worktree at dir that are
+// excluded by .gitignore, relative to dir. Wholly ignored directories are
+// listed once with a trailing slash rather than file by file.
+func IgnoredFiles(dir string) ([]string, error) {
+	var buf, stderr bytes.Buffer
+	cmd := exec.Command("git", "-C", dir, "ls-files", "--others", "--ignored", "--exclude-standard", "--directory", "--no-empty-directory")
+	cmd.Stdout = &buf
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git ls-files failed: %w (%s)", err, strings.TrimSpace(stderr.String()))
+	}
+	var files []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
 }
 
 // BranchToDir converts a branch name into a filesystem-safe directory name.
@@ -372,15 +556,79 @@ func (a AddArgs) Build(worktreePath string) []string {
 	return out
 }
 
-// buildAddArgs parses user args and returns transformed args for git worktree add
-// plus the derived worktree path (worktree dir derived from the branch name).
-func buildAddArgs(args []string, baseDir string) (gitArgs []string, worktreePath string, err error) {
-	a, err := ParseAddArgs(args)
-	if err != nil {
-		return nil, "", err
+// Args returns a as `gwt add` arguments, the inverse of ParseAddArgs.
+func (a AddArgs) Args() []string {
+	if a.BranchFlag == "" {
+		return append(append([]string{}, a.Flags...), a.Branch)
 	}
-	worktreePath = filepath.Join(baseDir, BranchToDir(a.Branch))
-	return a.Build(worktreePath), worktreePath, nil
+	return append(append([]string{}, a.Flags...), a.Extra...)
+}
+
+// missingRef reports whether `git worktree add` stderr says the branch
+// ("invalid reference") or the start point of a new branch ("not a valid
+// object name") does not exist.
+func missingRef(stderr string) bool {
+	return strings.Contains(stderr, "invalid reference:") || strings.Contains(stderr, "not a valid object name:")
+}
+
+// NewBranchArgs returns the AddArgs for creating branch from startPoint.
+func NewBranchArgs(branch, startPoint string) AddArgs {
+	return AddArgs{Flags: []string{"-b", branch}, BranchFlag: "-b", Branch: branch, Extra: []string{startPoint}}
+}
+
+// CheckoutArgs returns the AddArgs that put branch in a new worktree of
+// repoDir whether or not it exists yet: the local branch if there is one,
+// else a new branch tracking origin's, or failing that upstream's, branch of
+// that name (fetched first unless offline), else a new branch from mainRef.
+func CheckoutArgs(repoDir, branch, mainRef string, offline bool) AddArgs {
+	if refExists(repoDir, "refs/heads/"+branch) {
+		return AddArgs{Branch: branch}
+	}
+	remotes, _ := Remotes(repoDir)
+	var candidates []string
+	for _, remote := range []string{"origin", UpstreamRemote} {
+		if slices.Contains(remotes, remote) {
+			candidates = append(candidates, remote)
+		}
+	}
+	tracking := func(remote string) (AddArgs, bool) {
+		if !refExists(repoDir, "refs/remotes/"+remote+"/"+branch) {
+			return AddArgs{}, false
+		}
+		return AddArgs{
+			Flags:      []string{"--track", "-b", branch},
+			BranchFlag: "-b",
+			Branch:     branch,
+			Extra:      []string{remote + "/" + branch},
+		}, true
+	}
+	for _, remote := range candidates {
+		if a, ok := tracking(remote); ok {
+			return a
+		}
+	}
+	if !offline {
+		for _, remote := range candidates {
+			_ = exec.Command("git", "-C", repoDir, "fetch", "--quiet", remote, remoteRefspec(remote, branch)).Run()
+			if a, ok := tracking(remote); ok {
+				return a
+			}
+		}
+	}
+	return NewBranchArgs(branch, mainRef)
+}
+
+// startRef returns the ref a must resolve: the start point when creating a
+// branch, otherwise the branch to check out. Returns "" when a new branch has
+// no explicit start point.
+func (a AddArgs) startRef() string {
+	if a.BranchFlag == "" {
+		return a.Branch
+	}
+	if len(a.Extra) > 0 {
+		return a.Extra[0]
+	}
+	return ""
 }
 
 func repoName(url string) string {
@@ -400,7 +648,59 @@ func repoName(url string) string {
 	return n
 }
 
-func Clone(url, dir string) (_ string, retErr error) {
+// CloneOptions narrows what Clone downloads. The zero value is a full clone.
+type CloneOptions struct {
+	Filter       string // partial-clone filter, e.g. "blob:none"
+	Depth        int    // history depth; 0 for full history
+	SingleBranch bool   // track only the remote's default branch
+	Reference    string // local repository to borrow objects from
+	Dissociate   bool   // copy borrowed objects so the clone does not depend on Reference
+	Upstream     string // URL of the repository a fork was made from, added as UpstreamRemote
+}
+
+// cloneArgs returns the `git clone` flags for o.
+func (o CloneOptions) cloneArgs() []string {
+	var args []string
+	if o.Filter != "" {
+		args = append(args, "--filter="+o.Filter)
+	}
+	if o.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(o.Depth))
+	}
+	if o.SingleBranch {
+		args = append(args, "--single-branch")
+	}
+	if o.Reference != "" {
+		args = append(args, "--reference", o.Reference)
+	}
+	if o.Dissociate {
+		args = append(args, "--dissociate")
+	}
+	return args
+}
+
+// ObjectStore returns the absolute git directory holding the objects of the
+// repository at path — the common dir, so a worktree, a bare-layout root, or
+// a .git directory all resolve to the same store. Suitable for --reference.
+func ObjectStore(path string) (string, error) {
+	dir, err := gitOutput(path, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("%s is not a git repository: %w", path, err)
+	}
+	return dir, nil
+}
+
+// fetchArgs returns the `git fetch <remote>` arguments for o. The filter needs
+// no flag: it is recorded as remote.<remote>.partialclonefilter.
+func (o CloneOptions) fetchArgs(remote string) []string {
+	args := []string{"fetch", remote}
+	if o.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(o.Depth))
+	}
+	return args
+}
+
+func Clone(url, dir string, opts CloneOptions) (_ string, retErr error) {
 	if dir == "" {
 		dir = repoName(url)
 	}
@@ -421,7 +721,8 @@ func Clone(url, dir string) (_ string, retErr error) {
 		}
 	}()
 
-	cloneCmd := exec.Command("git", "clone", "--bare", url, ".bare")
+	cloneArgs := append(append([]string{"clone", "--bare"}, opts.cloneArgs()...), url, ".bare")
+	cloneCmd := exec.Command("git", cloneArgs...)
 	cloneCmd.Dir = absDir
 	cloneCmd.Stdout = os.Stdout
 	cloneCmd.Stderr = os.Stderr
@@ -434,12 +735,20 @@ func Clone(url, dir string) (_ string, retErr error) {
 		return "", fmt.Errorf("failed to write .git file: %w", err)
 	}
 
+	// A bare clone's HEAD is the remote's default branch. An empty remote
+	// has none, so head may be "".
+	head, _ := gitOutput(absDir, "symbolic-ref", "--quiet", "--short", "HEAD")
+
 	repo := &Repo{Dir: absDir, IsBare: true}
-	if err := repo.ConfigureFetch(); err != nil {
+	if opts.SingleBranch && head != "" {
+		if err := repo.setFetchRefspec("origin", remoteRefspec("origin", head)); err != nil {
+			return "", fmt.Errorf("failed to configure fetch: %w", err)
+		}
+	} else if err := repo.ConfigureFetch(); err != nil {
 		return "", fmt.Errorf("failed to configure fetch: %w", err)
 	}
 
-	fetchCmd := exec.Command("git", "fetch", "origin")
+	fetchCmd := exec.Command("git", opts.fetchArgs("origin")...)
 	fetchCmd.Dir = absDir
 	fetchCmd.Stdout = os.Stdout
 	fetchCmd.Stderr = os.Stderr
@@ -447,9 +756,63 @@ func Clone(url, dir string) (_ string, retErr error) {
 		return "", fmt.Errorf("git fetch failed: %w", err)
 	}
 
+	// Record HEAD as origin/HEAD so DefaultBranch finds it like in a regular
+	// clone. Best-effort.
+	if head != "" {
+		_ = exec.Command("git", "-C", absDir, "remote", "set-head", "origin", head).Run()
+	}
+
+	if opts.Upstream != "" {
+		if err := addUpstream(absDir, opts); err != nil {
+			return "", err
+		}
+	}
+
 	return absDir, nil
 }
 
+// addUpstream adds opts.Upstream as UpstreamRemote of the clone at repoDir,
+// names the repo after it (see CanonicalName), and fetches it with the same filter, depth and single-branch narrowing as
+// origin.
+func addUpstream(repoDir string, opts CloneOptions) error {
+	if _, err := gitOutput(repoDir, "remote", "add", UpstreamRemote, opts.Upstream); err != nil {
+		return err
+	}
+	if _, err := gitOutput(repoDir, "config", nameRemoteConfig, UpstreamRemote); err != nil {
+		return err
+	}
+	if opts.Filter != "" {
+		if _, err := gitOutput(repoDir, "config", "remote."+UpstreamRemote+".promisor", "true"); err != nil {
+			return err
+		}
+		if _, err := gitOutput(repoDir, "config", "remote."+UpstreamRemote+".partialclonefilter", opts.Filter); err != nil {
+			return err
+		}
+	}
+	refspec := remoteRefspec(UpstreamRemote, "*")
+	if opts.SingleBranch {
+		if out, err := gitOutput(repoDir, "ls-remote", "--symref", UpstreamRemote, "HEAD"); err == nil {
+			if branch := parseSymrefHead(out); branch != "" {
+				refspec = remoteRefspec(UpstreamRemote, branch)
+			}
+		}
+	}
+	repo := &Repo{Dir: repoDir, IsBare: true}
+	if err := repo.setFetchRefspec(UpstreamRemote, refspec); err != nil {
+		return fmt.Errorf("failed to configure fetch: %w", err)
+	}
+
+	fetchCmd := exec.Command("git", opts.fetchArgs(UpstreamRemote)...)
+	fetchCmd.Dir = repoDir
+	fetchCmd.Stdout = os.Stdout
+	fetchCmd.Stderr = os.Stderr
+	if err := fetchCmd.Run(); err != nil {
+		return fmt.Errorf("git fetch %s failed: %w", UpstreamRemote, err)
+	}
+	_ = exec.Command("git", "-C", repoDir, "remote", "set-head", UpstreamRemote, "--auto").Run()
+	return nil
+}
+
 func ExitCode(err error) int {
 	if err == nil {
 		return 0
@@ -461,12 +824,6 @@ func ExitCode(err error) int {
 	return 1
 }
 
-func WriteCdFile(path string) {
-	if cdFile := os.Getenv("GWT_CD_FILE"); cdFile != "" && path != "" {
-		_ = os.WriteFile(cdFile, []byte(path), 0o644)
-	}
-}
-
 func (r *Repo) HooksDir() (string, error) {
 	var buf, stderr bytes.Buffer
 	cmd := exec.Command("git", "rev-parse", "--git-common-dir")
@@ -483,26 +840,78 @@ func (r *Repo) HooksDir() (string, error) {
 	return filepath.Join(commonDir, "hooks"), nil
 }
 
+// ConfigureFetch gives every remote the standard fetch refspec, which a bare
+// clone does not record, so `git fetch` updates its remote-tracking branches.
+// Single-branch refspecs written by Clone and hand-configured multi-value
+// refspecs are left alone.
 func (r *Repo) ConfigureFetch() error {
-	var buf bytes.Buffer
-	cmd := exec.Command("git", "config", "remote.origin.fetch")
-	cmd.Dir = r.Dir
-	cmd.Stdout = &buf
-	err := cmd.Run()
+	remotes, err := Remotes(r.Dir)
+	if err != nil {
+		return err
+	}
+	for _, remote := range remotes {
+		expected := remoteRefspec(remote, "*")
+		current, err := gitOutput(r.Dir, "config", "--get-all", "remote."+remote+".fetch")
+		if err == nil && (current == expected || isSingleBranchRefspec(remote, current) || strings.Contains(current, "\n")) {
+			continue
+		}
+		if err := r.setFetchRefspec(remote, expected); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	expected := "+refs/heads/*:refs/remotes/origin/*"
-	if err == nil && strings.TrimSpace(buf.String()) == expected {
-		return nil
+// UnconfigureFetch undoes ConfigureFetch and Clone's refspec setup: it drops
+// the fetch refspec of every remote whose refspec is one gwt writes (all
+// branches, or the single branch of a --single-branch clone), leaving the
+// remote as a plain bare clone has it. It returns the remotes changed.
+func (r *Repo) UnconfigureFetch() ([]string, error) {
+	remotes, err := Remotes(r.Dir)
+	if err != nil {
+		return nil, err
 	}
+	var changed []string
+	for _, remote := range remotes {
+		current, err := gitOutput(r.Dir, "config", "--get-all", "remote."+remote+".fetch")
+		if err != nil || (current != remoteRefspec(remote, "*") && !isSingleBranchRefspec(remote, current)) {
+			continue
+		}
+		if _, err := gitOutput(r.Dir, "config", "--unset-all", "remote."+remote+".fetch"); err != nil {
+			return changed, err
+		}
+		changed = append(changed, remote)
+	}
+	return changed, nil
+}
 
-	cmd = exec.Command("git", "config", "remote.origin.fetch", expected)
+func (r *Repo) setFetchRefspec(remote, refspec string) error {
+	cmd := exec.Command("git", "config", "remote."+remote+".fetch", refspec)
 	cmd.Dir = r.Dir
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to set remote.origin.fetch: %w", err)
+		return fmt.Errorf("failed to set remote.%s.fetch: %w", remote, err)
 	}
 	return nil
 }
 
+// remoteRefspec returns the fetch refspec mapping branch ("*" for all) of
+// remote to refs/remotes/<remote>/.
+func remoteRefspec(remote, branch string) string {
+	return "+refs/heads/" + branch + ":refs/remotes/" + remote + "/" + branch
+}
+
+// isSingleBranchRefspec reports whether refspec tracks a single branch of
+// remote, as written by a --single-branch Clone, which ConfigureFetch must
+// not widen.
+func isSingleBranchRefspec(remote, refspec string) bool {
+	branch, ok := strings.CutPrefix(refspec, "+refs/heads/")
+	if !ok || strings.Contains(branch, "*") {
+		return false
+	}
+	branch, _, _ = strings.Cut(branch, ":")
+	return branch != "" && refspec == remoteRefspec(remote, branch)
+}
+
 // WorktreeEntry represents a single worktree from `git worktree list --porcelain`.
 type WorktreeEntry struct {
 	Path   string
@@ -561,11 +970,12 @@ func decorateLine(content string, active, color bool) string {
 }
 
 // renderWorktreeTable renders the worktree list as an aligned table with the
-// active worktree marked. Columns are path | [size] | sha | annotation. When
-// sizes is nil the size column and total row are omitted (bare `ls`);
-// otherwise sizes[i] corresponds to infos[i] and a size column plus a total
-// row are included (`ls -s`).
-func renderWorktreeTable(infos []WorktreeInfo, sizes []disk.Result, activePath string, color bool) string {
+// active worktree marked. Columns are path | [size] | [last used] | sha |
+// annotation. When sizes is nil the size column and total row are omitted
+// (bare `ls`); otherwise sizes[i] corresponds to infos[i] and a size column
+// plus a total row are included (`ls -s`). Likewise used[i], when used is
+// not nil, fills the last-used column (`ls --sort=recent`).
+func renderWorktreeTable(infos []WorktreeInfo, sizes []disk.Result, used []string, activePath string, color bool) string {
 	withSize := sizes != nil
 
 	pathW := 0
@@ -574,6 +984,7 @@ func renderWorktreeTable(infos []WorktreeInfo, sizes []disk.Result, activePath s
 	}
 	shaW := 0
 	sizeW := 0
+	usedW := 0
 	sizeStrs := make([]string, len(infos))
 	var totalBytes int64
 	anyApprox := false
@@ -594,6 +1005,9 @@ func renderWorktreeTable(infos []WorktreeInfo, sizes []disk.Result, activePath s
 				anyApprox = true
 			}
 		}
+		if used != nil && len(used[i]) > usedW {
+			usedW = len(used[i])
+		}
 	}
 
 	totalStr := ""
@@ -606,14 +1020,14 @@ func renderWorktreeTable(infos []WorktreeInfo, sizes []disk.Result, activePath s
 
 	var b strings.Builder
 	for i, in := range infos {
-		var content string
+		content := fmt.Sprintf("%-*s  ", pathW, in.Path)
 		if withSize {
-			content = fmt.Sprintf("%-*s  %*s  %-*s  %s",
-				pathW, in.Path, sizeW, sizeStrs[i], shaW, in.SHA, in.Annotation())
-		} else {
-			content = fmt.Sprintf("%-*s  %-*s  %s",
-				pathW, in.Path, shaW, in.SHA, in.Annotation())
+			content += fmt.Sprintf("%*s  ", sizeW, sizeStrs[i])
+		}
+		if used != nil {
+			content += fmt.Sprintf("%-*s  ", usedW, used[i])
 		}
+		content += fmt.Sprintf("%-*s  %s", shaW, in.SHA, in.Annotation())
 		active := activePath != "" && in.Path == activePath
 		b.WriteString(decorateLine(strings.TrimRight(content, " "), active, color) + "\n")
 	}
@@ -624,45 +1038,78 @@ func renderWorktreeTable(infos []WorktreeInfo, sizes []disk.Result, activePath s
 	return b.String()
 }
 
+// ListOptions adds columns to, and reorders, the worktree list printed by
+// PrintWorktreeList.
+type ListOptions struct {
+	// Size adds an on-disk size column and a total row. Sizes are computed
+	// concurrently across worktrees.
+	Size bool
+	// LastUsed, when not nil, maps worktree paths to when they were last
+	// switched to. It adds a last-used column and lists the most recently
+	// used worktrees first; the others keep git's order after them.
+	LastUsed map[string]time.Time
+}
+
 // PrintWorktreeList prints the worktree list with the worktree containing the
 // caller's current directory marked. It is self-rendered from
 // `git worktree list --porcelain` (via ListWorktreesFull) at functional parity
 // with git's plain output. Color is enabled only on a terminal and when
 // NO_COLOR is unset.
-func (r *Repo) PrintWorktreeList() error {
+func (r *Repo) PrintWorktreeList(opts ListOptions) error {
 	infos, err := r.ListWorktreesFull()
 	if err != nil {
 		return err
 	}
-	fmt.Print(renderWorktreeTable(infos, nil, currentWorktreeTop(), shouldColor()))
+	var used []string
+	if opts.LastUsed != nil {
+		sort.SliceStable(infos, func(i, j int) bool {
+			return opts.LastUsed[infos[i].Path].After(opts.LastUsed[infos[j].Path])
+		})
+		now := time.Now()
+		used = make([]string, len(infos))
+		for i, in := range infos {
+			used[i] = "never used"
+			if t, ok := opts.LastUsed[in.Path]; ok {
+				used[i] = "used just now"
+				if d := now.Sub(t); d >= time.Minute {
+					used[i] = "used " + FormatAge(d) + " ago"
+				}
+			}
+		}
+	}
+	var sizes []disk.Result
+	if opts.Size {
+		sizes = make([]disk.Result, len(infos))
+		var wg sync.WaitGroup
+		for i := range infos {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				res, _ := disk.Size(infos[i].Path) // best-effort; errors → zero size
+				sizes[i] = res
+			}(i)
+		}
+		wg.Wait()
+	}
+	fmt.Print(renderWorktreeTable(infos, sizes, used, CurrentWorktreeTop(), shouldColor()))
 	return nil
 }
 
-// PrintSizedWorktreeList prints the worktree list with an on-disk size column.
-// Sizes are computed concurrently across worktrees.
-func (r *Repo) PrintSizedWorktreeList() error {
-	infos, err := r.ListWorktreesFull()
-	if err != nil {
-		return err
+// FormatAge renders d in its largest whole unit: days, hours or minutes.
+func FormatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
 	}
-	sizes := make([]disk.Result, len(infos))
-	var wg sync.WaitGroup
-	for i := range infos {
-		wg.Add(1)
-		go func(i int) {
-			defer wg.Done()
-			res, _ := disk.Size(infos[i].Path) // best-effort; errors → zero size
-			sizes[i] = res
-		}(i)
-	}
-	wg.Wait()
-	fmt.Print(renderWorktreeTable(infos, sizes, currentWorktreeTop(), shouldColor()))
-	return nil
 }
 
-// currentWorktreeTop returns the top-level path of the worktree containing the
+// CurrentWorktreeTop returns the top-level path of the worktree containing the
 // process's current directory, or "" when not inside a worktree.
-func currentWorktreeTop() string {
+func CurrentWorktreeTop() string {
 	var buf bytes.Buffer
 	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
 	cmd.Stdout = &buf
@@ -672,6 +1119,29 @@ func currentWorktreeTop() string {
 	return strings.TrimSpace(buf.String())
 }
 
+// SubdirIn returns the directory in the worktree at target that sits where
+// the current directory sits in its own worktree (main/packages/api ->
+// <target>/packages/api), or its nearest existing parent. It is target itself
+// when the current directory is a worktree root, not in a worktree, or in
+// another repository than target, whose layout says nothing about target's.
+func SubdirIn(target string) string {
+	prefix, err := gitOutput(".", "rev-parse", "--show-prefix")
+	if err != nil || prefix == "" {
+		return target
+	}
+	here, err := ObjectStore(".")
+	if there, thereErr := ObjectStore(target); err != nil || thereErr != nil || here != there {
+		return target
+	}
+	for rel := filepath.Clean(prefix); rel != "." && rel != string(filepath.Separator); rel = filepath.Dir(rel) {
+		dir := filepath.Join(target, rel)
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			return dir
+		}
+	}
+	return target
+}
+
 // shouldColor reports whether colored output should be emitted: true only when
 // stdout is a terminal and NO_COLOR is unset.
 func shouldColor() bool {
@@ -699,6 +1169,41 @@ func (r *Repo) FindWorktreeByBranch(branch string) (string, bool, error) {
 	return "", false, nil
 }
 
+// IsMerged reports whether rev is reachable from into, so merging it would
+// change nothing.
+func IsMerged(repoDir, rev, into string) bool {
+	return exec.Command("git", "-C", repoDir, "merge-base", "--is-ancestor", rev, into).Run() == nil
+}
+
+// SameCommit reports whether revs a and b both resolve to the same commit.
+func SameCommit(repoDir, a, b string) bool {
+	ca, errA := gitOutput(repoDir, "rev-parse", "--verify", "--quiet", a+"^{commit}")
+	cb, errB := gitOutput(repoDir, "rev-parse", "--verify", "--quiet", b+"^{commit}")
+	return errA == nil && errB == nil && ca == cb
+}
+
+// LastActivity returns when the worktree at worktreePath was last worked in:
+// the later of its HEAD commit's committer date and the last change to its
+// HEAD reflog (checkouts, commits, resets). The index is no guide: git status
+// rewrites it.
+func LastActivity(worktreePath string) (time.Time, error) {
+	out, err := gitOutput(worktreePath, "log", "-1", "--format=%ct", "HEAD")
+	if err != nil {
+		return time.Time{}, err
+	}
+	secs, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected commit date %q", out)
+	}
+	last := time.Unix(secs, 0)
+	if reflog, err := gitOutput(worktreePath, "rev-parse", "--path-format=absolute", "--git-path", "logs/HEAD"); err == nil {
+		if fi, err := os.Stat(reflog); err == nil && fi.ModTime().After(last) {
+			last = fi.ModTime()
+		}
+	}
+	return last, nil
+}
+
 // shaAbbrevLen is the abbreviated-SHA width shown in the sized worktree list.
 const shaAbbrevLen = 11
 
@@ -712,6 +1217,7 @@ type WorktreeInfo struct {
 	Bare     bool
 	Locked   bool
 	Prunable bool
+	PR       int // pull request the branch was fetched from by FetchPullRequest, or 0
 }
 
 // Annotation renders the trailing column git shows for this worktree.
@@ -731,6 +1237,9 @@ func (w WorktreeInfo) Annotation() string {
 	if w.Prunable {
 		a += " prunable"
 	}
+	if w.PR > 0 {
+		a += fmt.Sprintf(" PR #%d", w.PR)
+	}
 	return a
 }
 
@@ -776,7 +1285,7 @@ func parseWorktreeListFull(output string) []WorktreeInfo {
 }
 
 // ListWorktreesFull returns every worktree (including detached/bare) with its
-// abbreviated sha and lock/prune flags.
+// abbreviated sha, lock/prune flags and pull request number.
 func (r *Repo) ListWorktreesFull() ([]WorktreeInfo, error) {
 	var buf, stderr bytes.Buffer
 	cmd := exec.Command("git", "worktree", "list", "--porcelain")
@@ -786,5 +1295,18 @@ func (r *Repo) ListWorktreesFull() ([]WorktreeInfo, error) {
 	if err := cmd.Run(); err != nil {
 		return nil, fmt.Errorf("git worktree list failed: %w (%s)", err, strings.TrimSpace(stderr.String()))
 	}
-	return parseWorktreeListFull(buf.String()), nil
+	infos := parseWorktreeListFull(buf.String())
+	if prs := PullRequests(r.Dir); len(prs) > 0 {
+		for i := range infos {
+			infos[i].PR = prs[infos[i].Branch]
+		}
+	}
+	return infos, nil
+}
+
+// IsDirty reports whether the worktree at path has uncommitted changes or
+// untracked files. A worktree whose status cannot be read counts as clean.
+func IsDirty(path string) bool {
+	out, err := gitOutput(path, "status", "--porcelain")
+	return err == nil && out != ""
 }
@@ -6,8 +6,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/nicwestvold/gwt/disk"
 )
@@ -33,104 +36,6 @@ func TestBranchToDir(t *testing.T) {
 	}
 }
 
-func TestBuildAddArgs(t *testing.T) {
-	baseDir := "/repo"
-
-	tests := []struct {
-		name     string
-		args     []string
-		wantArgs []string
-		wantPath string
-		wantErr  bool
-	}{
-		{
-			name:     "existing branch with slash",
-			args:     []string{"fix/thing"},
-			wantArgs: []string{"/repo/fix-thing", "fix/thing"},
-			wantPath: "/repo/fix-thing",
-		},
-		{
-			name:     "-b flag",
-			args:     []string{"-b", "feat/x"},
-			wantArgs: []string{"-b", "feat/x", "/repo/feat-x"},
-			wantPath: "/repo/feat-x",
-		},
-		{
-			name:     "-B flag",
-			args:     []string{"-B", "feat/x"},
-			wantArgs: []string{"-B", "feat/x", "/repo/feat-x"},
-			wantPath: "/repo/feat-x",
-		},
-		{
-			name:     "-b with start-point",
-			args:     []string{"-b", "feat/x", "origin/main"},
-			wantArgs: []string{"-b", "feat/x", "/repo/feat-x", "origin/main"},
-			wantPath: "/repo/feat-x",
-		},
-		{
-			name:     "boolean flag before -b",
-			args:     []string{"--track", "-b", "feat/x"},
-			wantArgs: []string{"--track", "-b", "feat/x", "/repo/feat-x"},
-			wantPath: "/repo/feat-x",
-		},
-		{
-			name:     "--reason value flag",
-			args:     []string{"--reason", "lock", "feat/x"},
-			wantArgs: []string{"--reason", "lock", "/repo/feat-x", "feat/x"},
-			wantPath: "/repo/feat-x",
-		},
-		{
-			name:     "simple branch no slash",
-			args:     []string{"develop"},
-			wantArgs: []string{"/repo/develop", "develop"},
-			wantPath: "/repo/develop",
-		},
-		{
-			name:     "deeply nested branch",
-			args:     []string{"a/b/c/d"},
-			wantArgs: []string{"/repo/a-b-c-d", "a/b/c/d"},
-			wantPath: "/repo/a-b-c-d",
-		},
-		{
-			name:     "--orphan flag",
-			args:     []string{"--orphan", "feat/x"},
-			wantArgs: []string{"--orphan", "feat/x", "/repo/feat-x"},
-			wantPath: "/repo/feat-x",
-		},
-		{
-			name:    "empty args",
-			args:    []string{},
-			wantErr: true,
-		},
-		{
-			name:    "-b with no value",
-			args:    []string{"-b"},
-			wantErr: true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			gotArgs, gotPath, err := buildAddArgs(tt.args, baseDir)
-			if tt.wantErr {
-				if err == nil {
-					t.Fatalf("buildAddArgs(%v) expected error, got nil", tt.args)
-				}
-				return
-			}
-			if err != nil {
-				t.Fatalf("buildAddArgs(%v) unexpected error: %v", tt.args, err)
-			}
-			if gotPath != tt.wantPath {
-				t.Errorf("buildAddArgs(%v) path = %q, want %q", tt.args, gotPath, tt.wantPath)
-			}
-			if fmt.Sprintf("%v", gotArgs) != fmt.Sprintf("%v", tt.wantArgs) {
-				t.Errorf("buildAddArgs(%v) args = %v, want %v", tt.args, gotArgs, tt.wantArgs)
-			}
-		})
-	}
-}
-
 func TestExitCode(t *testing.T) {
 	tests := []struct {
 		name string
@@ -177,39 +82,33 @@ func TestRepoName(t *testing.T) {
 	}
 }
 
-func TestWriteCdFile(t *testing.T) {
-	t.Run("writes path when env var is set", func(t *testing.T) {
-		cdFile := filepath.Join(t.TempDir(), "cd-target")
-		t.Setenv("GWT_CD_FILE", cdFile)
-
-		WriteCdFile("/some/path")
-
-		data, err := os.ReadFile(cdFile)
-		if err != nil {
-			t.Fatalf("failed to read cd file: %v", err)
+func TestIgnoredFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "repo")
+	initRepoWithMain(t, dir)
+	for name, content := range map[string]string{
+		".gitignore":       ".env*\nnode_modules/\n",
+		".env":             "A=1",
+		".env.local":       "B=2",
+		"node_modules/x/y": "z",
+		"untracked.txt":    "not ignored",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
 		}
-		if string(data) != "/some/path" {
-			t.Errorf("cd file = %q, want %q", string(data), "/some/path")
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
 		}
-	})
-
-	t.Run("no-op when env var is unset", func(t *testing.T) {
-		t.Setenv("GWT_CD_FILE", "")
-
-		// Should not panic or create any file
-		WriteCdFile("/some/path")
-	})
-
-	t.Run("no-op when path is empty", func(t *testing.T) {
-		cdFile := filepath.Join(t.TempDir(), "cd-target")
-		t.Setenv("GWT_CD_FILE", cdFile)
-
-		WriteCdFile("")
+	}
 
-		if _, err := os.Stat(cdFile); err == nil {
-			t.Error("cd file should not exist when path is empty")
-		}
-	})
+	got, err := IgnoredFiles(dir)
+	if err != nil {
+		t.Fatalf("IgnoredFiles() error: %v", err)
+	}
+	want := []string{".env", ".env.local", "node_modules/"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("IgnoredFiles() = %v, want %v", got, want)
+	}
 }
 
 func TestNewRepo(t *testing.T) {
@@ -455,7 +354,7 @@ func TestAdd(t *testing.T) {
 	run("git", "-C", project, "fetch", "origin")
 
 	t.Run("success on first attempt", func(t *testing.T) {
-		got, err := repo.Add([]string{"main"}, project)
+		got, err := repo.Add([]string{"main"}, project, AddOptions{})
 		if err != nil {
 			t.Fatalf("Add([\"main\"]) unexpected error: %v", err)
 		}
@@ -473,7 +372,7 @@ func TestAdd(t *testing.T) {
 		run("git", "-C", sourceDir, "checkout", "-b", "new-feature")
 		run("git", "-C", sourceDir, "commit", "--allow-empty", "-m", "new feature")
 
-		got, err := repo.Add([]string{"new-feature"}, project)
+		got, err := repo.Add([]string{"new-feature"}, project, AddOptions{})
 		if err != nil {
 			t.Fatalf("Add([\"new-feature\"]) unexpected error: %v", err)
 		}
@@ -486,8 +385,36 @@ func TestAdd(t *testing.T) {
 		}
 	})
 
+	t.Run("fetches only the missing branch and tracks it", func(t *testing.T) {
+		run("git", "-C", sourceDir, "branch", "wanted")
+		run("git", "-C", sourceDir, "branch", "unrelated")
+
+		got, err := repo.Add([]string{"wanted"}, project, AddOptions{})
+		if err != nil {
+			t.Fatalf("Add([\"wanted\"]) unexpected error: %v", err)
+		}
+		if refExists(project, "refs/remotes/origin/unrelated") {
+			t.Error("fetched origin/unrelated, want only the requested branch")
+		}
+		if out, _ := gitOutput(got, "rev-parse", "--abbrev-ref", "@{upstream}"); out != "origin/wanted" {
+			t.Errorf("upstream of wanted = %q, want origin/wanted", out)
+		}
+	})
+
+	t.Run("offline never fetches", func(t *testing.T) {
+		run("git", "-C", sourceDir, "branch", "later")
+
+		_, err := repo.Add([]string{"later"}, project, AddOptions{Offline: true})
+		if err == nil || !strings.Contains(err.Error(), "offline") {
+			t.Fatalf("Add(offline) error = %v, want offline failure", err)
+		}
+		if refExists(project, "refs/remotes/origin/later") {
+			t.Error("offline add fetched origin/later")
+		}
+	})
+
 	t.Run("fails cleanly for nonexistent branch", func(t *testing.T) {
-		_, err := repo.Add([]string{"totally-fake"}, project)
+		_, err := repo.Add([]string{"totally-fake"}, project, AddOptions{})
 		if err == nil {
 			t.Fatal("Add([\"totally-fake\"]) expected error, got nil")
 		}
@@ -551,7 +478,7 @@ func TestRemove(t *testing.T) {
 		wtDir := filepath.Join(project, "feat-remove-test")
 		run("git", "-C", project, "worktree", "add", "-b", "remove-test", wtDir)
 
-		res, err := repo.Remove([]string{wtDir}, false)
+		res, err := repo.Remove([]string{wtDir}, RemoveOptions{})
 		if err != nil {
 			t.Fatalf("Remove() error: %v", err)
 		}
@@ -577,9 +504,14 @@ func TestRemove(t *testing.T) {
 		// Make a commit so the branch diverges from main.
 		run("git", "-C", wtDir, "commit", "--allow-empty", "-m", "diverge")
 
-		_, err := repo.Remove([]string{wtDir}, false)
-		if err != nil {
-			t.Fatalf("Remove() error: %v", err)
+		// The commit is on no remote, so removal needs --force.
+		_, err := repo.Remove([]string{wtDir}, RemoveOptions{})
+		var lossErr *WorkLossError
+		if !errors.As(err, &lossErr) || len(lossErr.Loss.Unpushed) != 1 {
+			t.Fatalf("Remove() error = %v, want WorkLossError with one unpushed commit", err)
+		}
+		if _, err := repo.Remove([]string{"--force", wtDir}, RemoveOptions{}); err != nil {
+			t.Fatalf("Remove(--force) error: %v", err)
 		}
 		// Branch should still exist because it's not fully merged.
 		out, _ := exec.Command("git", "-C", project, "branch", "--list", "unmerged-branch").Output()
@@ -595,7 +527,7 @@ func TestRemove(t *testing.T) {
 		// Create an untracked file to make it dirty
 		_ = os.WriteFile(filepath.Join(wtDir, "dirty.txt"), []byte("dirty"), 0o644)
 
-		res, err := repo.Remove([]string{"--force", wtDir}, false)
+		res, err := repo.Remove([]string{"--force", wtDir}, RemoveOptions{})
 		if err != nil {
 			t.Fatalf("Remove(--force) error: %v", err)
 		}
@@ -619,7 +551,7 @@ func TestRemove(t *testing.T) {
 		}
 		t.Cleanup(func() { _ = os.Chdir(origDir) })
 
-		res, err := repo.Remove([]string{}, false)
+		res, err := repo.Remove([]string{}, RemoveOptions{})
 		if err != nil {
 			t.Fatalf("Remove() auto-detect error: %v", err)
 		}
@@ -635,7 +567,7 @@ func TestRemove(t *testing.T) {
 		wtDir := filepath.Join(project, "feat-keep-branch")
 		run("git", "-C", project, "worktree", "add", "-b", "keep-branch-test", wtDir)
 
-		res, err := repo.Remove([]string{wtDir}, true)
+		res, err := repo.Remove([]string{wtDir}, RemoveOptions{KeepBranch: true})
 		if err != nil {
 			t.Fatalf("Remove(keepBranch=true) error: %v", err)
 		}
@@ -656,7 +588,7 @@ func TestRemove(t *testing.T) {
 	})
 
 	t.Run("refuses to remove main working tree", func(t *testing.T) {
-		_, err := repo.Remove([]string{project}, false)
+		_, err := repo.Remove([]string{project}, RemoveOptions{})
 		if err == nil {
 			t.Fatal("Remove(project) should error when targeting main working tree")
 		}
@@ -1033,6 +965,7 @@ func TestWorktreeInfoAnnotation(t *testing.T) {
 		{WorktreeInfo{Bare: true}, "(bare)"},
 		{WorktreeInfo{Branch: "x", Locked: true}, "[x] locked"},
 		{WorktreeInfo{Branch: "x", Locked: true, Prunable: true}, "[x] locked prunable"},
+		{WorktreeInfo{Branch: "pr/7", PR: 7}, "[pr/7] PR #7"},
 	}
 	for _, c := range cases {
 		if got := c.in.Annotation(); got != c.want {
@@ -1050,7 +983,7 @@ func TestRenderWorktreeTableSized(t *testing.T) {
 		{Bytes: 4831838208},             // ~4.5 GiB
 		{Bytes: 1288490188, Skipped: 2}, // ~1.2 GiB, approximate
 	}
-	out := renderWorktreeTable(infos, sizes, "/repo/main", false)
+	out := renderWorktreeTable(infos, sizes, nil, "/repo/main", false)
 
 	if !strings.Contains(out, "* /repo/main") {
 		t.Errorf("active marker missing:\n%s", out)
@@ -1069,7 +1002,7 @@ func TestRenderWorktreeTableSized(t *testing.T) {
 func TestRenderWorktreeTableSizedColorActiveRow(t *testing.T) {
 	infos := []WorktreeInfo{{Path: "/repo/main", SHA: "abc", Branch: "main"}}
 	sizes := []disk.Result{{Bytes: 1024}}
-	out := renderWorktreeTable(infos, sizes, "/repo/main", true)
+	out := renderWorktreeTable(infos, sizes, nil, "/repo/main", true)
 	if !strings.Contains(out, "\033[32m") {
 		t.Errorf("expected green on active row:\n%q", out)
 	}
@@ -1086,7 +1019,7 @@ func TestRenderWorktreeTableBare(t *testing.T) {
 	}
 
 	// Bare mode: no size column, no total row.
-	out := renderWorktreeTable(infos, nil, "/repo/main", false)
+	out := renderWorktreeTable(infos, nil, nil, "/repo/main", false)
 	if strings.Contains(out, "total") {
 		t.Errorf("bare mode should have no total row:\n%s", out)
 	}
@@ -1104,21 +1037,28 @@ func TestRenderWorktreeTableBare(t *testing.T) {
 	}
 
 	// Color gating on the active row.
-	colored := renderWorktreeTable(infos, nil, "/repo/main", true)
+	colored := renderWorktreeTable(infos, nil, nil, "/repo/main", true)
 	if !strings.Contains(colored, green+"* /repo/main") || !strings.Contains(colored, reset) {
 		t.Errorf("active row not green:\n%q", colored)
 	}
 
 	// No active path (e.g. run outside any worktree): every row indented, none starred.
-	noActive := renderWorktreeTable(infos, nil, "", false)
+	noActive := renderWorktreeTable(infos, nil, nil, "", false)
 	for _, line := range strings.Split(strings.TrimRight(noActive, "\n"), "\n") {
 		if strings.HasPrefix(line, "* ") {
 			t.Errorf("no active path should mark no row, got:\n%s", noActive)
 		}
 	}
 
+	// Last-used column sits between the path and the sha.
+	used := []string{"used 5m ago", "never used", "never used", "used 2d ago"}
+	withUsed := renderWorktreeTable(infos, nil, used, "/repo/main", false)
+	if !strings.Contains(withUsed, "* /repo/main         used 5m ago  27233475638  [main]") {
+		t.Errorf("last-used column misplaced:\n%s", withUsed)
+	}
+
 	// Empty input yields an empty string.
-	if got := renderWorktreeTable(nil, nil, "", false); got != "" {
+	if got := renderWorktreeTable(nil, nil, nil, "", false); got != "" {
 		t.Errorf("empty infos = %q, want \"\"", got)
 	}
 }
@@ -1135,3 +1075,380 @@ func exitState(t *testing.T, code int) *os.ProcessState {
 	}
 	return exitErr.ProcessState
 }
+
+func TestCloneOptionsArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      CloneOptions
+		wantClone []string
+		wantFetch []string
+	}{
+		{"zero value", CloneOptions{}, nil, []string{"fetch", "origin"}},
+		{"filter", CloneOptions{Filter: "blob:none"}, []string{"--filter=blob:none"}, []string{"fetch", "origin"}},
+		{"depth", CloneOptions{Depth: 1}, []string{"--depth", "1"}, []string{"fetch", "origin", "--depth", "1"}},
+		{"all", CloneOptions{Filter: "tree:0", Depth: 5, SingleBranch: true},
+			[]string{"--filter=tree:0", "--depth", "5", "--single-branch"}, []string{"fetch", "origin", "--depth", "5"}},
+		{"reference", CloneOptions{Reference: "/src/.bare", Dissociate: true},
+			[]string{"--reference", "/src/.bare", "--dissociate"}, []string{"fetch", "origin"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.cloneArgs(); !reflect.DeepEqual(got, tt.wantClone) {
+				t.Errorf("cloneArgs() = %v, want %v", got, tt.wantClone)
+			}
+			if got := tt.opts.fetchArgs("origin"); !reflect.DeepEqual(got, tt.wantFetch) {
+				t.Errorf("fetchArgs() = %v, want %v", got, tt.wantFetch)
+			}
+		})
+	}
+}
+
+func TestIsSingleBranchRefspec(t *testing.T) {
+	tests := []struct {
+		refspec string
+		want    bool
+	}{
+		{"+refs/heads/main:refs/remotes/origin/main", true},
+		{"+refs/heads/release/v2:refs/remotes/origin/release/v2", true},
+		{"+refs/heads/*:refs/remotes/origin/*", false},
+		{"+refs/heads/main:refs/remotes/upstream/main", false},
+		{"refs/heads/main:refs/remotes/origin/main", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isSingleBranchRefspec("origin", tt.refspec); got != tt.want {
+			t.Errorf("isSingleBranchRefspec(%q) = %v, want %v", tt.refspec, got, tt.want)
+		}
+	}
+}
+
+func TestCloneSingleBranchShallow(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "source")
+	initRepoWithMain(t, source)
+	testRunGit(t, "git", "-C", source, "commit", "--allow-empty", "-m", "second")
+	testRunGit(t, "git", "-C", source, "branch", "other")
+
+	dir, err := Clone("file://"+source, filepath.Join(tmp, "project"), CloneOptions{Depth: 1, SingleBranch: true})
+	if err != nil {
+		t.Fatalf("Clone() error: %v", err)
+	}
+
+	if out, _ := gitOutput(dir, "config", "remote.origin.fetch"); out != "+refs/heads/main:refs/remotes/origin/main" {
+		t.Errorf("remote.origin.fetch = %q, want main only", out)
+	}
+	if BranchExists(dir, "other") {
+		t.Error("single-branch clone fetched branch other")
+	}
+	if out, _ := gitOutput(dir, "rev-list", "--count", "origin/main"); out != "1" {
+		t.Errorf("origin/main history = %s commits, want 1", out)
+	}
+
+	// gwt init reconfigures fetch in bare repos; it must not widen the refspec.
+	repo := &Repo{Dir: dir, IsBare: true}
+	if err := repo.ConfigureFetch(); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := gitOutput(dir, "config", "remote.origin.fetch"); out != "+refs/heads/main:refs/remotes/origin/main" {
+		t.Errorf("after ConfigureFetch remote.origin.fetch = %q, want main only", out)
+	}
+}
+
+func TestLastActivity(t *testing.T) {
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "repo")
+	initRepoWithMain(t, dir)
+	wt := filepath.Join(tmp, "feat")
+	testRunGit(t, "git", "-C", dir, "worktree", "add", "-q", "-b", "feat", wt)
+
+	// Age the commit and the reflog as if the worktree sat idle for a month.
+	month := time.Now().Add(-30 * 24 * time.Hour)
+	cmd := exec.Command("git", "-C", wt, "commit", "-q", "--allow-empty", "-m", "old")
+	cmd.Env = append(testGitEnv(), "GIT_COMMITTER_DATE="+month.Format(time.RFC3339))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("commit: %v\n%s", err, out)
+	}
+	reflog, err := gitOutput(wt, "rev-parse", "--path-format=absolute", "--git-path", "logs/HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(reflog, month, month); err != nil {
+		t.Fatal(err)
+	}
+
+	// git status rewrites the index; that is not activity.
+	testRunGit(t, "git", "-C", wt, "status", "--porcelain")
+	last, err := LastActivity(wt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(last) < 29*24*time.Hour {
+		t.Errorf("LastActivity() = %v after git status, want about a month ago", last)
+	}
+
+	testRunGit(t, "git", "-C", wt, "checkout", "-q", "-b", "again")
+	if last, _ := LastActivity(wt); time.Since(last) > time.Hour {
+		t.Errorf("LastActivity() = %v after a checkout, want now", last)
+	}
+}
+
+func TestUnconfigureFetch(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "source")
+	initRepoWithMain(t, source)
+
+	dir := filepath.Join(tmp, "project.git")
+	testRunGit(t, "git", "clone", "-q", "--bare", source, dir)
+	repo := &Repo{Dir: dir, IsBare: true}
+	if err := repo.ConfigureFetch(); err != nil {
+		t.Fatal(err)
+	}
+	testRunGit(t, "git", "-C", dir, "remote", "add", "mine", source)
+	testRunGit(t, "git", "-C", dir, "config", "remote.mine.fetch", "+refs/heads/*:refs/remotes/mine/custom/*")
+	changed, err := repo.UnconfigureFetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changed, []string{"origin"}) {
+		t.Errorf("UnconfigureFetch() = %v, want [origin]", changed)
+	}
+	if out, err := gitOutput(dir, "config", "remote.origin.fetch"); err == nil {
+		t.Errorf("remote.origin.fetch = %q after UnconfigureFetch, want unset", out)
+	}
+	// A refspec gwt did not write is left alone.
+	if out, _ := gitOutput(dir, "config", "remote.mine.fetch"); out != "+refs/heads/*:refs/remotes/mine/custom/*" {
+		t.Errorf("remote.mine.fetch = %q, want it untouched", out)
+	}
+}
+
+func TestAddSparse(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "source")
+	initRepoWithMain(t, source)
+	for _, f := range []string{"app/a.txt", "lib/b.txt"} {
+		if err := os.MkdirAll(filepath.Join(source, filepath.Dir(f)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(source, f), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testRunGit(t, "git", "-C", source, "add", ".")
+	testRunGit(t, "git", "-C", source, "commit", "-m", "dirs")
+
+	dir, err := Clone(source, filepath.Join(tmp, "project"), CloneOptions{})
+	if err != nil {
+		t.Fatalf("Clone() error: %v", err)
+	}
+	hooksDir := filepath.Join(dir, ".bare", "hooks")
+	hookLog := filepath.Join(tmp, "hook.log")
+	script := "#!/bin/sh\necho \"$1 $3\" >> " + hookLog + "\n"
+	if err := os.WriteFile(filepath.Join(hooksDir, "post-checkout"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &Repo{Dir: dir, IsBare: true}
+	wt, err := repo.Add([]string{"-b", "feat", "origin/main"}, dir, AddOptions{Sparse: []string{"app"}})
+	if err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(wt, "app", "a.txt")); err != nil {
+		t.Errorf("app/a.txt not checked out: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(wt, "lib")); !os.IsNotExist(err) {
+		t.Errorf("lib/ checked out in sparse worktree (stat err %v)", err)
+	}
+	if _, err := os.Stat(filepath.Join(wt, "README")); err != nil {
+		t.Errorf("top-level README missing from cone checkout: %v", err)
+	}
+
+	log, err := os.ReadFile(hookLog)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	if got := string(log); got != nullSHA+" 1\n" {
+		t.Errorf("hook invocations = %q, want one new-worktree call", got)
+	}
+
+	// Other worktrees are unaffected by the sparse profile.
+	full, err := repo.Add([]string{"main"}, dir, AddOptions{})
+	if err != nil {
+		t.Fatalf("Add(main) error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(full, "lib", "b.txt")); err != nil {
+		t.Errorf("full worktree missing lib/b.txt: %v", err)
+	}
+}
+
+func TestCloneReference(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "source")
+	initRepoWithMain(t, source)
+	local := filepath.Join(tmp, "local")
+	if _, err := Clone(source, local, CloneOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := ObjectStore(local)
+	if err != nil {
+		t.Fatalf("ObjectStore() error: %v", err)
+	}
+	want, _ := filepath.EvalSymlinks(filepath.Join(local, ".bare"))
+	if got, _ := filepath.EvalSymlinks(store); got != want {
+		t.Errorf("ObjectStore() = %q, want %q", store, want)
+	}
+
+	alternates := func(dir string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, ".bare", "objects", "info", "alternates"))
+		return string(data)
+	}
+	borrowed, err := Clone("file://"+source, filepath.Join(tmp, "borrowed"), CloneOptions{Reference: store})
+	if err != nil {
+		t.Fatalf("Clone(--reference) error: %v", err)
+	}
+	if got := alternates(borrowed); !strings.Contains(got, filepath.Join(store, "objects")) {
+		t.Errorf("alternates = %q, want the reference's object dir", got)
+	}
+
+	standalone, err := Clone("file://"+source, filepath.Join(tmp, "standalone"), CloneOptions{Reference: store, Dissociate: true})
+	if err != nil {
+		t.Fatalf("Clone(--dissociate) error: %v", err)
+	}
+	if got := alternates(standalone); got != "" {
+		t.Errorf("dissociated clone still has alternates %q", got)
+	}
+	if out, err := gitOutput(standalone, "rev-parse", "--verify", "origin/main"); err != nil || out == "" {
+		t.Errorf("dissociated clone missing origin/main: %v", err)
+	}
+}
+
+func TestStartRef(t *testing.T) {
+	tests := []struct {
+		a    AddArgs
+		want string
+	}{
+		{AddArgs{Branch: "feature"}, "feature"},
+		{NewBranchArgs("feat", "upstream/main"), "upstream/main"},
+		{AddArgs{Flags: []string{"-b", "feat"}, BranchFlag: "-b", Branch: "feat"}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.a.startRef(); got != tt.want {
+			t.Errorf("%+v.startRef() = %q, want %q", tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestCloneUpstream(t *testing.T) {
+	tmp := t.TempDir()
+	upstream := filepath.Join(tmp, "upstream")
+	initRepoWithMain(t, upstream)
+	fork := filepath.Join(tmp, "fork")
+	testRunGit(t, "git", "clone", "--quiet", "--bare", upstream, fork)
+
+	dir, err := Clone(fork, filepath.Join(tmp, "project"), CloneOptions{Upstream: upstream})
+	if err != nil {
+		t.Fatalf("Clone() error: %v", err)
+	}
+	for _, remote := range []string{"origin", "upstream"} {
+		if out, _ := gitOutput(dir, "config", "remote."+remote+".fetch"); out != remoteRefspec(remote, "*") {
+			t.Errorf("remote.%s.fetch = %q", remote, out)
+		}
+	}
+	if !BranchExists(dir, "main") {
+		t.Error("origin/main missing after clone")
+	}
+	if !(&Repo{Dir: dir}).NamedAfterUpstream() {
+		t.Error("fork clone is not named after upstream")
+	}
+
+	// A start point on upstream that has not been fetched yet is fetched
+	// from upstream, not origin.
+	testRunGit(t, "git", "-C", upstream, "checkout", "--quiet", "-b", "release")
+	testRunGit(t, "git", "-C", upstream, "commit", "--quiet", "--allow-empty", "-m", "release")
+	repo := &Repo{Dir: dir, IsBare: true}
+	wt, err := repo.Add([]string{"-b", "feat", "upstream/release"}, dir, AddOptions{})
+	if err != nil {
+		t.Fatalf("Add(upstream/release) error: %v", err)
+	}
+	want, _ := gitOutput(upstream, "rev-parse", "release")
+	if got, _ := gitOutput(wt, "rev-parse", "HEAD"); got != want {
+		t.Errorf("worktree HEAD = %s, want upstream release %s", got, want)
+	}
+}
+
+func TestSubdirIn(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoDir := filepath.Join(root, "repo")
+	initRepoWithMain(t, repoDir)
+	target := filepath.Join(root, "target")
+	testRunGit(t, "git", "-C", repoDir, "worktree", "add", "-q", "-b", "target", target)
+	other := filepath.Join(root, "other")
+	initRepoWithMain(t, other)
+	for _, dir := range []string{filepath.Join(repoDir, "packages", "api", "src"), filepath.Join(target, "packages", "api"), filepath.Join(other, "packages", "api")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Chdir(filepath.Join(repoDir, "packages", "api"))
+	if got, want := SubdirIn(target), filepath.Join(target, "packages", "api"); got != want {
+		t.Errorf("SubdirIn() = %q, want %q", got, want)
+	}
+	// Missing in the target: the nearest existing parent.
+	t.Chdir(filepath.Join(repoDir, "packages", "api", "src"))
+	if got, want := SubdirIn(target), filepath.Join(target, "packages", "api"); got != want {
+		t.Errorf("SubdirIn() from src = %q, want %q", got, want)
+	}
+	// Another repository's layout says nothing about this one's.
+	if got := SubdirIn(other); got != other {
+		t.Errorf("SubdirIn() of another repo = %q, want %q", got, other)
+	}
+	t.Chdir(repoDir)
+	if got := SubdirIn(target); got != target {
+		t.Errorf("SubdirIn() from the root = %q, want %q", got, target)
+	}
+	t.Chdir(root)
+	if got := SubdirIn(target); got != target {
+		t.Errorf("SubdirIn() outside a worktree = %q, want %q", got, target)
+	}
+}
+
+func TestCheckoutArgs(t *testing.T) {
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote")
+	initRepoWithMain(t, remote)
+	testRunGit(t, "git", "-C", remote, "branch", "on-remote")
+	local := filepath.Join(root, "local")
+	testRunGit(t, "git", "clone", "--quiet", remote, local)
+	testRunGit(t, "git", "-C", local, "branch", "local-only")
+	// Forget the remote-tracking ref so CheckoutArgs has to fetch it.
+	testRunGit(t, "git", "-C", local, "update-ref", "-d", "refs/remotes/origin/on-remote")
+	upstream := filepath.Join(root, "upstream")
+	initRepoWithMain(t, upstream)
+	testRunGit(t, "git", "-C", upstream, "branch", "on-upstream")
+	testRunGit(t, "git", "-C", local, "remote", "add", UpstreamRemote, upstream)
+
+	tests := []struct {
+		branch  string
+		offline bool
+		want    []string
+	}{
+		{"local-only", false, []string{"local-only"}},
+		{"on-remote", true, []string{"-b", "on-remote", "origin/main"}},
+		{"on-remote", false, []string{"--track", "-b", "on-remote", "origin/on-remote"}},
+		{"on-upstream", false, []string{"--track", "-b", "on-upstream", "upstream/on-upstream"}},
+		{"brand-new", false, []string{"-b", "brand-new", "origin/main"}},
+	}
+	for _, tt := range tests {
+		a := CheckoutArgs(local, tt.branch, "origin/main", tt.offline)
+		if got := a.Args(); !slices.Equal(got, tt.want) {
+			t.Errorf("CheckoutArgs(%q, offline=%v).Args() = %v, want %v", tt.branch, tt.offline, got, tt.want)
+		}
+		if parsed, err := ParseAddArgs(a.Args()); err != nil || parsed.Branch != tt.branch || parsed.BranchFlag != a.BranchFlag {
+			t.Errorf("ParseAddArgs(%v) = %+v, %v; want it to round-trip", a.Args(), parsed, err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// prConfigKey is the per-branch git config key recording the pull request a
+// branch was fetched from, e.g. branch.pr/123.gwt-pr = 123.
+const prConfigKey = "gwt-pr"
+
+// PRBranch returns the local branch name used for pull request number.
+func PRBranch(number int) string {
+	return "pr/" + strconv.Itoa(number)
+}
+
+// PullRequestRef returns the ref under which the host of remoteURL publishes
+// the head of pull request number: refs/merge-requests/<n>/head for GitLab,
+// refs/pull/<n>/head (GitHub's layout) for everything else.
+func PullRequestRef(remoteURL string, number int) string {
+	if strings.Contains(remoteHost(remoteURL), "gitlab") {
+		return fmt.Sprintf("refs/merge-requests/%d/head", number)
+	}
+	return fmt.Sprintf("refs/pull/%d/head", number)
+}
+
+// remoteHost extracts the host name from a remote URL in the formats
+// ParseCanonicalName accepts. Returns "" for local paths.
+func remoteHost(rawURL string) string {
+	var host string
+	if _, rest, ok := strings.Cut(rawURL, "://"); ok {
+		host, _, _ = strings.Cut(rest, "/")
+	} else if idx := strings.Index(rawURL, ":"); idx >= 0 && !strings.Contains(rawURL[:idx], "/") {
+		host = rawURL[:idx]
+	} else {
+		return ""
+	}
+	if _, h, ok := strings.Cut(host, "@"); ok {
+		host = h
+	}
+	host, _, _ = strings.Cut(host, ":")
+	return strings.ToLower(host)
+}
+
+// prRemote returns the remote pull requests are opened against: upstream for
+// a fork, otherwise origin.
+func prRemote(repoDir string) string {
+	if _, err := gitOutput(repoDir, "remote", "get-url", UpstreamRemote); err == nil {
+		return UpstreamRemote
+	}
+	return "origin"
+}
+
+// FetchPullRequest fetches the head of pull request number into the local
+// branch PRBranch(number), sets that branch to pull from the pull request,
+// and records the number for PullRequests. An existing branch is only
+// fast-forwarded; one that has diverged (the pull request was force-pushed,
+// or it has local commits) is an error saying how to reset it. Returns the
+// branch name.
+func FetchPullRequest(repoDir string, number int) (string, error) {
+	remote := prRemote(repoDir)
+	url, err := gitOutput(repoDir, "remote", "get-url", remote)
+	if err != nil {
+		return "", fmt.Errorf("no %s remote to fetch pull requests from", remote)
+	}
+	ref := PullRequestRef(url, number)
+	branch := PRBranch(number)
+
+	// An existing branch is fetched into FETCH_HEAD first, so a pull request
+	// that was force-pushed is reported rather than failing git's
+	// fast-forward check.
+	exists := refExists(repoDir, "refs/heads/"+branch)
+	refspec := "+" + ref + ":refs/heads/" + branch
+	if exists {
+		refspec = ref
+	}
+	cmd := exec.Command("git", "-C", repoDir, "fetch", remote, refspec)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to fetch pull request %d (%s from %s): %w", number, ref, remote, err)
+	}
+	if exists {
+		head, err := gitOutput(repoDir, "rev-parse", "--verify", "FETCH_HEAD^{commit}")
+		if err != nil {
+			return "", err
+		}
+		if !IsMerged(repoDir, "refs/heads/"+branch, head) {
+			return "", fmt.Errorf("%s has commits that are not in pull request %d (was it force-pushed?); to reset %s to the pull request, discarding them, run:\n  git -C %s fetch %s +%s:refs/heads/%s", branch, number, branch, repoDir, remote, ref, branch)
+		}
+		if _, err := gitOutput(repoDir, "update-ref", "-m", "gwt: fast-forward to pull request", "refs/heads/"+branch, head); err != nil {
+			return "", err
+		}
+	}
+
+	for key, value := range map[string]string{"remote": remote, "merge": ref, prConfigKey: strconv.Itoa(number)} {
+		if _, err := gitOutput(repoDir, "config", "branch."+branch+"."+key, value); err != nil {
+			return "", err
+		}
+	}
+	return branch, nil
+}
+
+// PullRequests maps branch names to the pull request numbers recorded for
+// them by FetchPullRequest. gwt ls shows them next to the branch.
+func PullRequests(repoDir string) map[string]int {
+	out, err := gitOutput(repoDir, "config", "--get-regexp", `^branch\..*\.`+prConfigKey+`$`)
+	if err != nil {
+		return nil
+	}
+	prs := map[string]int{}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		branch := strings.TrimSuffix(strings.TrimPrefix(key, "branch."), "."+prConfigKey)
+		if n, err := strconv.Atoi(value); err == nil {
+			prs[branch] = n
+		}
+	}
+	return prs
+}
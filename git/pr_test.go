@@ -0,0 +1,105 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPullRequestRef(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"git@github.com:org/repo.git", "refs/pull/7/head"},
+		{"https://github.com/org/repo", "refs/pull/7/head"},
+		{"git@gitlab.com:group/sub/repo.git", "refs/merge-requests/7/head"},
+		{"https://gitlab.example.com/group/repo.git", "refs/merge-requests/7/head"},
+		{"ssh://git@gitlab.internal:2222/group/repo.git", "refs/merge-requests/7/head"},
+		{"/srv/git/gitlab/repo.git", "refs/pull/7/head"},
+	}
+	for _, tt := range tests {
+		if got := PullRequestRef(tt.url, 7); got != tt.want {
+			t.Errorf("PullRequestRef(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestFetchPullRequest(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "source")
+	initRepoWithMain(t, source)
+	commit := func(msg string) string {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(source, "README"), []byte(msg), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		testRunGit(t, "git", "-C", source, "commit", "--quiet", "-am", msg)
+		sha, err := gitOutput(source, "rev-parse", "HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sha
+	}
+	remote := filepath.Join(root, "remote.git")
+	testRunGit(t, "git", "clone", "--quiet", "--bare", source, remote)
+	testRunGit(t, "git", "-C", source, "checkout", "--quiet", "-b", "contrib")
+	first := commit("first")
+	testRunGit(t, "git", "-C", source, "push", "--quiet", remote, "contrib:refs/pull/7/head")
+
+	clone := filepath.Join(root, "clone")
+	testRunGit(t, "git", "clone", "--quiet", remote, clone)
+
+	branch, err := FetchPullRequest(clone, 7)
+	if err != nil {
+		t.Fatalf("FetchPullRequest() error: %v", err)
+	}
+	if branch != "pr/7" {
+		t.Errorf("branch = %q, want pr/7", branch)
+	}
+	if sha, _ := gitOutput(clone, "rev-parse", "pr/7"); sha != first {
+		t.Errorf("pr/7 = %s, want %s", sha, first)
+	}
+	if merge, _ := gitOutput(clone, "config", "branch.pr/7.merge"); merge != "refs/pull/7/head" {
+		t.Errorf("branch.pr/7.merge = %q", merge)
+	}
+	if prs := PullRequests(clone); prs["pr/7"] != 7 || len(prs) != 1 {
+		t.Errorf("PullRequests() = %v, want map[pr/7:7]", prs)
+	}
+
+	// A second fetch fast-forwards the existing branch.
+	second := commit("second")
+	testRunGit(t, "git", "-C", source, "push", "--quiet", remote, "contrib:refs/pull/7/head")
+	if _, err := FetchPullRequest(clone, 7); err != nil {
+		t.Fatalf("FetchPullRequest() refetch error: %v", err)
+	}
+	if sha, _ := gitOutput(clone, "rev-parse", "pr/7"); sha != second {
+		t.Errorf("pr/7 after refetch = %s, want %s", sha, second)
+	}
+
+	// After a force-push the branch is left alone, with a way to reset it.
+	testRunGit(t, "git", "-C", source, "reset", "--quiet", "--hard", first)
+	commit("rewritten")
+	testRunGit(t, "git", "-C", source, "push", "--quiet", "--force", remote, "contrib:refs/pull/7/head")
+	if _, err := FetchPullRequest(clone, 7); err == nil || !strings.Contains(err.Error(), "force-pushed") || !strings.Contains(err.Error(), "+refs/pull/7/head:refs/heads/pr/7") {
+		t.Errorf("FetchPullRequest() after a force-push: err = %v, want one explaining how to reset", err)
+	}
+	if sha, _ := gitOutput(clone, "rev-parse", "pr/7"); sha != second {
+		t.Errorf("pr/7 after a refused refetch = %s, want %s", sha, second)
+	}
+
+	wt := filepath.Join(root, "wt")
+	testRunGit(t, "git", "-C", clone, "worktree", "add", "--quiet", wt, "pr/7")
+	infos, err := (&Repo{Dir: clone}).ListWorktreesFull()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].PR != 0 || infos[1].PR != 7 {
+		t.Errorf("ListWorktreesFull() PRs = %+v, want 0 then 7", infos)
+	}
+
+	if _, err := FetchPullRequest(clone, 8); err == nil {
+		t.Error("FetchPullRequest(missing) succeeded, want error")
+	}
+}
@@ -0,0 +1,111 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// setupFailedFile, in a worktree's git dir, records that its post-checkout
+// hook failed when gwt created it. gwt setup clears it by re-running the
+// hook successfully; it also goes away with the worktree.
+const setupFailedFile = "gwt-setup-failed"
+
+// WorktreeState is what a shell prompt shows about the worktree containing a
+// directory. ReadWorktreeState fills it from the files under .git without
+// running git, so it is cheap enough to compute for every prompt.
+type WorktreeState struct {
+	RepoDir     string // as Repo.Dir: the parent of .bare, or of .git in a regular repo
+	Worktree    string // top of the worktree; "" at the root of a bare-repo layout
+	Branch      string // "" when HEAD is detached
+	Head        string // abbreviated commit when HEAD is detached
+	SetupFailed bool
+}
+
+// ReadWorktreeState returns the state of the repository containing dir. ok
+// is false when dir is not inside one.
+func ReadWorktreeState(dir string) (state WorktreeState, ok bool) {
+	top, gitDir := "", ""
+	for d := dir; ; d = filepath.Dir(d) {
+		if g, found := gitDirAt(d); found {
+			top, gitDir = d, g
+			break
+		}
+		if filepath.Dir(d) == d {
+			return WorktreeState{}, false
+		}
+	}
+
+	commonDir := gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = strings.TrimSpace(string(data))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+	}
+	state.RepoDir = filepath.Dir(filepath.Clean(commonDir))
+	if gitDir == commonDir && filepath.Base(gitDir) != ".git" {
+		// The .git file at the root of a bare-repo layout points at .bare,
+		// whose HEAD is no worktree's.
+		return state, true
+	}
+	state.Worktree = top
+
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return state, true
+	}
+	ref := strings.TrimSpace(string(head))
+	if branch, found := strings.CutPrefix(ref, "ref: refs/heads/"); found {
+		state.Branch = branch
+	} else {
+		state.Head = ref[:min(len(ref), 7)]
+	}
+	_, err = os.Stat(filepath.Join(gitDir, setupFailedFile))
+	state.SetupFailed = err == nil
+	return state, true
+}
+
+// gitDirAt returns the git dir of the worktree whose top is dir: dir/.git
+// itself, or where a .git file points.
+func gitDirAt(dir string) (string, bool) {
+	p := filepath.Join(dir, ".git")
+	fi, err := os.Stat(p)
+	if err != nil {
+		return "", false
+	}
+	if fi.IsDir() {
+		return p, true
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return "", false
+	}
+	gitDir, found := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !found {
+		return "", false
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(dir, gitDir)
+	}
+	return filepath.Clean(gitDir), true
+}
+
+// markSetupFailed records in the worktree's git dir that its setup failed.
+// After `git worktree add` fails, call it only for a worktree that did not
+// exist beforehand: git removes a new worktree again when the checkout
+// itself fails, so one it left behind failed in its post-checkout hook.
+func markSetupFailed(worktreePath string, cause error) {
+	gitDir, ok := gitDirAt(worktreePath)
+	if !ok {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(gitDir, setupFailedFile), []byte(cause.Error()+"\n"), 0o644)
+}
+
+// clearSetupFailed removes the marker left by markSetupFailed, if any.
+func clearSetupFailed(worktreePath string) {
+	if gitDir, ok := gitDirAt(worktreePath); ok {
+		_ = os.Remove(filepath.Join(gitDir, setupFailedFile))
+	}
+}
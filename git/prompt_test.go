@@ -0,0 +1,109 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadWorktreeState(t *testing.T) {
+	tmp, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(tmp, "src")
+	initRepoWithMain(t, src)
+	if err := os.MkdirAll(filepath.Join(src, "pkg", "api"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Bare-repo layout, as gwt clone leaves it.
+	root := filepath.Join(tmp, "app")
+	testRunGit(t, "git", "clone", "-q", "--bare", src, filepath.Join(root, ".bare"))
+	if err := os.WriteFile(filepath.Join(root, ".git"), []byte("gitdir: ./.bare\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testRunGit(t, "git", "-C", root, "worktree", "add", "-q", filepath.Join(root, "main"), "main")
+	testRunGit(t, "git", "-C", root, "worktree", "add", "-q", "--detach", filepath.Join(root, "detached"), "main")
+	head, err := gitOutput(root, "rev-parse", "--short=7", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		want WorktreeState
+	}{
+		{"regular repo", filepath.Join(src, "pkg", "api"), WorktreeState{RepoDir: src, Worktree: src, Branch: "main"}},
+		{"bare layout root", root, WorktreeState{RepoDir: root}},
+		{"linked worktree", filepath.Join(root, "main"), WorktreeState{RepoDir: root, Worktree: filepath.Join(root, "main"), Branch: "main"}},
+		{"detached", filepath.Join(root, "detached"), WorktreeState{RepoDir: root, Worktree: filepath.Join(root, "detached"), Head: head}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ReadWorktreeState(tt.dir)
+			if !ok || got != tt.want {
+				t.Errorf("ReadWorktreeState(%s) = %+v, %v; want %+v", tt.dir, got, ok, tt.want)
+			}
+		})
+	}
+
+	if _, ok := ReadWorktreeState(tmp); ok {
+		t.Errorf("ReadWorktreeState(%s) outside a repo: ok = true", tmp)
+	}
+}
+
+func TestAddWorktreeMarksSetupFailure(t *testing.T) {
+	tmp, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(tmp, "repo")
+	initRepoWithMain(t, dir)
+	hook := filepath.Join(dir, ".git", "hooks", "post-checkout")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\nexit 3\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	wt := filepath.Join(tmp, "feat")
+	if err := AddWorktree(dir, NewBranchArgs("feat", "main"), wt, AddOptions{Offline: true}); err == nil {
+		t.Fatal("AddWorktree() with a failing hook succeeded")
+	}
+	if state, ok := ReadWorktreeState(wt); !ok || !state.SetupFailed || state.Branch != "feat" {
+		t.Errorf("ReadWorktreeState() after failed setup = %+v, %v; want feat with SetupFailed", state, ok)
+	}
+
+	if err := os.Rename(hook, hook+".off"); err != nil {
+		t.Fatal(err)
+	}
+	clean := filepath.Join(tmp, "ok")
+	if err := AddWorktree(dir, NewBranchArgs("ok", "main"), clean, AddOptions{Offline: true}); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := ReadWorktreeState(clean); state.SetupFailed {
+		t.Error("ReadWorktreeState() after successful setup: SetupFailed = true")
+	}
+
+	// Adding over an existing worktree fails, but says nothing about its setup.
+	if err := os.Rename(hook+".off", hook); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddWorktree(dir, AddArgs{Branch: "ok"}, clean, AddOptions{Offline: true}); err == nil {
+		t.Fatal("AddWorktree() over an existing worktree succeeded")
+	}
+	if state, _ := ReadWorktreeState(clean); state.SetupFailed {
+		t.Error("ReadWorktreeState() after re-adding a healthy worktree: SetupFailed = true")
+	}
+
+	// Re-running setup successfully clears the marker.
+	if err := os.Remove(hook); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunPostCheckout(wt); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := ReadWorktreeState(wt); state.SetupFailed {
+		t.Error("ReadWorktreeState() after RunPostCheckout succeeded: SetupFailed = true")
+	}
+}
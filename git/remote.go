@@ -2,27 +2,41 @@ package git
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os/exec"
 	"path/filepath"
 	"strings"
 )
 
+// UpstreamRemote is the remote a fork clone adds for the repository it was
+// forked from.
+const UpstreamRemote = "upstream"
+
+// nameRemoteConfig is the git config key naming the remote CanonicalName
+// parses, set to UpstreamRemote by a Clone with CloneOptions.Upstream. Other
+// repos are named after origin, whatever remotes they have.
+const nameRemoteConfig = "gwt.nameRemote"
+
 // CanonicalName returns the "owner/repo" identifier for this repository,
-// parsed from the origin remote URL. Falls back to the directory basename
-// if no origin remote is configured.
+// parsed from the URL of the origin remote, or of the upstream remote when
+// gwt cloned the repo as a fork (see NamedAfterUpstream), so the fork shares
+// the name and config of the project it was forked from. Falls back to the
+// directory basename if no such remote is configured.
 func (r *Repo) CanonicalName() (string, error) {
-	var buf, stderr bytes.Buffer
-	cmd := exec.Command("git", "config", "remote.origin.url")
-	cmd.Dir = r.Dir
-	cmd.Stdout = &buf
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		// No origin remote — fall back to directory name.
-		return filepath.Base(r.Dir), nil
+	remotes := []string{"origin"}
+	if r.NamedAfterUpstream() {
+		remotes = []string{UpstreamRemote, "origin"}
+	}
+	var url string
+	for _, remote := range remotes {
+		if out, err := gitOutput(r.Dir, "config", "remote."+remote+".url"); err == nil && out != "" {
+			url = out
+			break
+		}
 	}
-	url := strings.TrimSpace(buf.String())
 	if url == "" {
+		// No remote — fall back to directory name.
 		return filepath.Base(r.Dir), nil
 	}
 	name := ParseCanonicalName(url)
@@ -32,6 +46,23 @@ func (r *Repo) CanonicalName() (string, error) {
 	return name, nil
 }
 
+// NamedAfterUpstream reports whether CanonicalName uses the upstream remote:
+// the repo was cloned with CloneOptions.Upstream and has not been renamed by
+// NameAfterOrigin since.
+func (r *Repo) NamedAfterUpstream() bool {
+	out, err := gitOutput(r.Dir, "config", nameRemoteConfig)
+	return err == nil && out == UpstreamRemote
+}
+
+// NameAfterOrigin makes CanonicalName use the origin remote again.
+func (r *Repo) NameAfterOrigin() error {
+	if !r.NamedAfterUpstream() {
+		return nil
+	}
+	_, err := gitOutput(r.Dir, "config", "--unset-all", nameRemoteConfig)
+	return err
+}
+
 // ParseCanonicalName extracts "owner/repo" from a remote URL.
 // Supports HTTPS, SSH URL, and SSH shorthand formats.
 // For paths with more than two segments (e.g. GitLab nested groups),
@@ -67,3 +98,222 @@ func ParseCanonicalName(rawURL string) string {
 	}
 	return ""
 }
+
+// Remotes returns the names of the remotes configured in the repo at repoDir.
+func Remotes(repoDir string) ([]string, error) {
+	out, err := gitOutput(repoDir, "remote")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+// remoteForRef returns the remote whose remote-tracking branches ref names,
+// e.g. "upstream" for "upstream/main", or "origin" when it names none.
+func remoteForRef(repoDir, ref string) string {
+	remotes, _ := Remotes(repoDir)
+	best := ""
+	for _, remote := range remotes {
+		if strings.HasPrefix(ref, remote+"/") && len(remote) > len(best) {
+			best = remote
+		}
+	}
+	if best == "" {
+		return "origin"
+	}
+	return best
+}
+
+// splitRemoteRef splits ref into the remote it belongs to and the branch on
+// that remote: "upstream/main" gives ("upstream", "main"), while a plain
+// "feature" is taken as origin's branch ("origin", "feature").
+func splitRemoteRef(repoDir, ref string) (remote, branch string) {
+	remote = remoteForRef(repoDir, ref)
+	if rest, ok := strings.CutPrefix(ref, remote+"/"); ok {
+		return remote, rest
+	}
+	return remote, ref
+}
+
+// DefaultBranch returns the origin remote's default branch, reporting false
+// when it cannot be determined. It tries LocalDefaultBranch, then
+// `git ls-remote --symref origin HEAD`, which needs the network.
+func DefaultBranch(repoDir string) (string, bool) {
+	if branch, ok := LocalDefaultBranch(repoDir); ok {
+		return branch, true
+	}
+	if out, err := gitOutput(repoDir, "ls-remote", "--symref", "origin", "HEAD"); err == nil {
+		if branch := parseSymrefHead(out); branch != "" {
+			return branch, true
+		}
+	}
+	return "", false
+}
+
+// LocalDefaultBranch is DefaultBranch without the network: it reads
+// refs/remotes/origin/HEAD, then the HEAD of a bare repo (which a bare clone
+// copies from the remote).
+func LocalDefaultBranch(repoDir string) (string, bool) {
+	if out, err := gitOutput(repoDir, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		if branch := strings.TrimPrefix(out, "origin/"); branch != "" {
+			return branch, true
+		}
+	}
+	if out, err := gitOutput(repoDir, "rev-parse", "--is-bare-repository"); err == nil && out == "true" {
+		if head, err := gitOutput(repoDir, "symbolic-ref", "--quiet", "--short", "HEAD"); err == nil && head != "" {
+			return head, true
+		}
+	}
+	return "", false
+}
+
+// parseSymrefHead extracts the branch from `git ls-remote --symref` output,
+// whose first line reads "ref: refs/heads/<branch>\tHEAD".
+func parseSymrefHead(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		ref, target, ok := strings.Cut(line, "\t")
+		if ok && target == "HEAD" && strings.HasPrefix(ref, "ref: refs/heads/") {
+			return strings.TrimPrefix(ref, "ref: refs/heads/")
+		}
+	}
+	return ""
+}
+
+// ResolveMainBranch returns explicit when set, otherwise the origin remote's
+// default branch, falling back to "main".
+func ResolveMainBranch(repoDir, explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if branch, ok := DefaultBranch(repoDir); ok {
+		return branch
+	}
+	return "main"
+}
+
+// ResolveLocalMainBranch is ResolveMainBranch without the network, for
+// commands that must work offline. init and clone record the resolved branch
+// in the repo's config entry, so explicit is normally set.
+func ResolveLocalMainBranch(repoDir, explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if branch, ok := LocalDefaultBranch(repoDir); ok {
+		return branch
+	}
+	return "main"
+}
+
+// RemoteBranch is a branch on a remote, as seen through its remote-tracking
+// ref.
+type RemoteBranch struct {
+	Remote string
+	Branch string // branch name on the remote
+	SHA    string // commit the remote-tracking ref points at
+}
+
+func (b RemoteBranch) String() string {
+	return b.Remote + "/" + b.Branch
+}
+
+// PushedBranch returns the remote branch local branch pushes to: its
+// configured upstream branch, or origin/<branch> when it has none. ok is false
+// when there is no remote-tracking ref for it (never pushed, or pruned).
+func PushedBranch(repoDir, branch string) (RemoteBranch, bool) {
+	rb := RemoteBranch{Remote: "origin", Branch: branch}
+	if remote, err := gitOutput(repoDir, "config", "branch."+branch+".remote"); err == nil && remote != "." {
+		merge, _ := gitOutput(repoDir, "config", "branch."+branch+".merge")
+		if b, ok := strings.CutPrefix(merge, "refs/heads/"); ok {
+			rb.Remote, rb.Branch = remote, b
+		}
+	}
+	sha, err := gitOutput(repoDir, "rev-parse", "--verify", "--quiet", "refs/remotes/"+rb.String())
+	if err != nil {
+		return RemoteBranch{}, false
+	}
+	rb.SHA = sha
+	return rb, true
+}
+
+// RemoteDeletion reports the outcome of DeleteRemoteBranch. Both fields are
+// empty when the branch had no remote branch.
+type RemoteDeletion struct {
+	Deleted string // remote branch deleted, e.g. "origin/feat"
+	Kept    string // why the remote branch was left in place
+}
+
+// DeleteRemoteBranch deletes the remote branch local branch pushes to (see
+// PushedBranch). The remote's default branch is always kept. Unless force, a
+// remote branch that is not at the same commit as the local branch is kept,
+// and the push is leased on the remote-tracking ref so a branch updated on
+// the remote since the last fetch is kept too. A remote branch that is
+// already gone counts as deleted.
+func DeleteRemoteBranch(repoDir, branch string, force bool) RemoteDeletion {
+	rb, ok := PushedBranch(repoDir, branch)
+	if !ok {
+		return RemoteDeletion{}
+	}
+	if rb.Branch == remoteDefaultBranch(repoDir, rb.Remote) {
+		return RemoteDeletion{Kept: fmt.Sprintf("%s kept: it is the remote's default branch", rb)}
+	}
+	local, err := gitOutput(repoDir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	if err != nil {
+		return RemoteDeletion{Kept: fmt.Sprintf("%s kept: local branch %s not found", rb, branch)}
+	}
+	if !force && local != rb.SHA {
+		return RemoteDeletion{Kept: fmt.Sprintf("%s kept: it is at %.7s but %s is at %.7s; --force-remote deletes it anyway", rb, rb.SHA, branch, local)}
+	}
+
+	args := []string{"-C", repoDir, "push", "--quiet", rb.Remote, "--delete"}
+	if !force {
+		args = append(args, "--force-with-lease=refs/heads/"+rb.Branch+":"+rb.SHA)
+	}
+	args = append(args, "refs/heads/"+rb.Branch)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil && !remoteRefExists(repoDir, rb) {
+		_ = exec.Command("git", "-C", repoDir, "update-ref", "-d", "refs/remotes/"+rb.String()).Run()
+		return RemoteDeletion{Deleted: rb.String()}
+	}
+	switch {
+	case err == nil:
+		return RemoteDeletion{Deleted: rb.String()}
+	case strings.Contains(string(out), "stale info"):
+		return RemoteDeletion{Kept: fmt.Sprintf("%s kept: it changed on the remote since the last fetch; --force-remote deletes it anyway", rb)}
+	default:
+		return RemoteDeletion{Kept: fmt.Sprintf("%s kept: git push failed: %s", rb, strings.TrimSpace(string(out)))}
+	}
+}
+
+// remoteDefaultBranch returns the default branch of remote as known locally,
+// or "" when it is not known.
+func remoteDefaultBranch(repoDir, remote string) string {
+	if remote == "origin" {
+		branch, _ := LocalDefaultBranch(repoDir)
+		return branch
+	}
+	out, err := gitOutput(repoDir, "symbolic-ref", "--quiet", "--short", "refs/remotes/"+remote+"/HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(out, remote+"/")
+}
+
+// remoteRefExists reports whether rb still exists on its remote. It errs
+// towards true when the remote cannot be reached.
+func remoteRefExists(repoDir string, rb RemoteBranch) bool {
+	err := exec.Command("git", "-C", repoDir, "ls-remote", "--exit-code", rb.Remote, "refs/heads/"+rb.Branch).Run()
+	var exitErr *exec.ExitError
+	return !(errors.As(err, &exitErr) && exitErr.ExitCode() == 2)
+}
+
+// gitOutput runs git in repoDir and returns its trimmed stdout.
+func gitOutput(repoDir string, args ...string) (string, error) {
+	var buf, stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", repoDir}, args...)...)
+	cmd.Stdout = &buf
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w (%s)", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
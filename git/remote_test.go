@@ -3,6 +3,7 @@ package git
 import (
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -61,6 +62,31 @@ func TestCanonicalName(t *testing.T) {
 		}
 	})
 
+	t.Run("upstream remote wins in a fork clone", func(t *testing.T) {
+		repoDir := filepath.Join(t.TempDir(), "fork")
+		testRunGit(t, "git", "init", repoDir)
+		testRunGit(t, "git", "-C", repoDir, "remote", "add", "origin", "https://github.com/me/myrepo.git")
+		testRunGit(t, "git", "-C", repoDir, "remote", "add", "upstream", "git@github.com:owner/myrepo.git")
+		repo := &Repo{Dir: repoDir}
+
+		// An upstream remote alone does not rename a repo.
+		if got, err := repo.CanonicalName(); err != nil || got != "me/myrepo" {
+			t.Errorf("CanonicalName() = %q, %v; want %q", got, err, "me/myrepo")
+		}
+
+		testRunGit(t, "git", "-C", repoDir, "config", nameRemoteConfig, UpstreamRemote)
+		if got, err := repo.CanonicalName(); err != nil || got != "owner/myrepo" {
+			t.Errorf("CanonicalName() of a fork clone = %q, %v; want %q", got, err, "owner/myrepo")
+		}
+
+		if err := repo.NameAfterOrigin(); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := repo.CanonicalName(); err != nil || got != "me/myrepo" {
+			t.Errorf("CanonicalName() after NameAfterOrigin = %q, %v; want %q", got, err, "me/myrepo")
+		}
+	})
+
 	t.Run("without origin remote", func(t *testing.T) {
 		tmp := t.TempDir()
 		repoDir := filepath.Join(tmp, "localrepo")
@@ -78,3 +104,183 @@ func TestCanonicalName(t *testing.T) {
 		}
 	})
 }
+
+func TestDefaultBranch(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "source")
+	initRepoWithMain(t, source)
+	testRunGit(t, "git", "-C", source, "branch", "-m", "main", "develop")
+
+	clone := filepath.Join(tmp, "clone")
+	testRunGit(t, "git", "clone", "--quiet", source, clone)
+	if got, ok := DefaultBranch(clone); !ok || got != "develop" {
+		t.Errorf("DefaultBranch() = (%q, %v), want (develop, true)", got, ok)
+	}
+
+	if got, ok := DefaultBranch(source); ok {
+		t.Errorf("DefaultBranch() without origin = (%q, true), want false", got)
+	}
+}
+
+func TestDefaultBranchBare(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "source")
+	initRepoWithMain(t, source)
+	testRunGit(t, "git", "-C", source, "branch", "-m", "main", "trunk")
+
+	bare := filepath.Join(tmp, "bare.git")
+	testRunGit(t, "git", "clone", "--quiet", "--bare", source, bare)
+	if got, ok := DefaultBranch(bare); !ok || got != "trunk" {
+		t.Errorf("DefaultBranch() on bare clone = (%q, %v), want (trunk, true)", got, ok)
+	}
+}
+
+func TestParseSymrefHead(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"symref", "ref: refs/heads/develop\tHEAD\n0123abcd\tHEAD", "develop"},
+		{"slashed branch", "ref: refs/heads/release/v2\tHEAD", "release/v2"},
+		{"no symref", "0123abcd\tHEAD", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSymrefHead(tt.output); got != tt.want {
+				t.Errorf("parseSymrefHead() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveMainBranch(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "source")
+	initRepoWithMain(t, source)
+	testRunGit(t, "git", "-C", source, "branch", "-m", "main", "develop")
+	clone := filepath.Join(tmp, "clone")
+	testRunGit(t, "git", "clone", "--quiet", source, clone)
+
+	if got := ResolveMainBranch(clone, "release"); got != "release" {
+		t.Errorf("ResolveMainBranch() with explicit = %q, want release", got)
+	}
+	if got := ResolveMainBranch(clone, ""); got != "develop" {
+		t.Errorf("ResolveMainBranch() from origin/HEAD = %q, want develop", got)
+	}
+	if got := ResolveMainBranch(source, ""); got != "main" {
+		t.Errorf("ResolveMainBranch() without origin = %q, want main", got)
+	}
+
+	// Without origin/HEAD only ResolveMainBranch asks the remote.
+	testRunGit(t, "git", "-C", clone, "remote", "set-head", "origin", "--delete")
+	if got := ResolveMainBranch(clone, ""); got != "develop" {
+		t.Errorf("ResolveMainBranch() via ls-remote = %q, want develop", got)
+	}
+	if got := ResolveLocalMainBranch(clone, ""); got != "main" {
+		t.Errorf("ResolveLocalMainBranch() without origin/HEAD = %q, want main", got)
+	}
+	if got := ResolveLocalMainBranch(clone, "release"); got != "release" {
+		t.Errorf("ResolveLocalMainBranch() with explicit = %q, want release", got)
+	}
+}
+
+func TestRemoteForRef(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "repo")
+	testRunGit(t, "git", "init", "--quiet", "-b", "main", dir)
+	for _, remote := range []string{"origin", "upstream", "up"} {
+		testRunGit(t, "git", "-C", dir, "remote", "add", remote, "https://example.com/"+remote+".git")
+	}
+	tests := []struct{ ref, want string }{
+		{"upstream/main", "upstream"},
+		{"up/feature", "up"},
+		{"origin/main", "origin"},
+		{"feature/login", "origin"},
+		{"main", "origin"},
+		{"", "origin"},
+	}
+	for _, tt := range tests {
+		if got := remoteForRef(dir, tt.ref); got != tt.want {
+			t.Errorf("remoteForRef(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestSplitRemoteRef(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "repo")
+	testRunGit(t, "git", "init", "--quiet", "-b", "main", dir)
+	testRunGit(t, "git", "-C", dir, "remote", "add", "origin", "https://example.com/o.git")
+	testRunGit(t, "git", "-C", dir, "remote", "add", "upstream", "https://example.com/u.git")
+	tests := []struct{ ref, remote, branch string }{
+		{"upstream/main", "upstream", "main"},
+		{"origin/fix/typo", "origin", "fix/typo"},
+		{"fix/typo", "origin", "fix/typo"},
+	}
+	for _, tt := range tests {
+		remote, branch := splitRemoteRef(dir, tt.ref)
+		if remote != tt.remote || branch != tt.branch {
+			t.Errorf("splitRemoteRef(%q) = (%q, %q), want (%q, %q)", tt.ref, remote, branch, tt.remote, tt.branch)
+		}
+	}
+}
+
+func TestDeleteRemoteBranch(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "source")
+	initRepoWithMain(t, source)
+	remote := filepath.Join(root, "remote.git")
+	testRunGit(t, "git", "clone", "--quiet", "--bare", source, remote)
+	clone := filepath.Join(root, "clone")
+	testRunGit(t, "git", "clone", "--quiet", remote, clone)
+	run := func(args ...string) { testRunGit(t, "git", append([]string{"-C", clone}, args...)...) }
+	onRemote := func(branch string) bool {
+		_, err := gitOutput(remote, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+		return err == nil
+	}
+	push := func(branch string) {
+		run("branch", branch, "main")
+		run("push", "--quiet", "-u", "origin", branch)
+	}
+
+	push("merged")
+	if got := DeleteRemoteBranch(clone, "merged", false); got.Deleted != "origin/merged" || got.Kept != "" {
+		t.Errorf("DeleteRemoteBranch(in sync) = %+v, want deleted", got)
+	}
+	if onRemote("merged") {
+		t.Error("origin/merged still on the remote")
+	}
+
+	push("diverged")
+	run("checkout", "--quiet", "diverged")
+	run("commit", "--quiet", "--allow-empty", "-m", "local only")
+	if got := DeleteRemoteBranch(clone, "diverged", false); got.Deleted != "" || !strings.Contains(got.Kept, "origin/diverged kept") {
+		t.Errorf("DeleteRemoteBranch(diverged) = %+v, want kept", got)
+	}
+	if !onRemote("diverged") {
+		t.Error("diverged remote branch deleted without force")
+	}
+	if got := DeleteRemoteBranch(clone, "diverged", true); got.Deleted != "origin/diverged" {
+		t.Errorf("DeleteRemoteBranch(diverged, force) = %+v, want deleted", got)
+	}
+
+	push("gone")
+	testRunGit(t, "git", "-C", remote, "branch", "-D", "gone")
+	if got := DeleteRemoteBranch(clone, "gone", false); got.Deleted != "origin/gone" {
+		t.Errorf("DeleteRemoteBranch(already gone) = %+v, want deleted", got)
+	}
+
+	run("branch", "local-only", "main")
+	if got := DeleteRemoteBranch(clone, "local-only", false); got != (RemoteDeletion{}) {
+		t.Errorf("DeleteRemoteBranch(never pushed) = %+v, want zero", got)
+	}
+
+	for _, force := range []bool{false, true} {
+		if got := DeleteRemoteBranch(clone, "main", force); got.Deleted != "" || !strings.Contains(got.Kept, "default branch") {
+			t.Errorf("DeleteRemoteBranch(main, %v) = %+v, want kept as the default branch", force, got)
+		}
+	}
+	if !onRemote("main") {
+		t.Error("the remote's default branch was deleted")
+	}
+}
@@ -0,0 +1,113 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// WorkLoss lists the work in a worktree that removing it would discard or
+// leave stranded.
+type WorkLoss struct {
+	Uncommitted []string // tracked paths with staged or unstaged changes
+	Untracked   []string // untracked, non-ignored paths
+	Stashes     []string // stash entries made on the worktree's branch
+	Unpushed    []string // commits (oneline) reachable from HEAD but from no remote-tracking ref
+}
+
+// Empty reports whether nothing would be lost.
+func (l WorkLoss) Empty() bool {
+	return len(l.Uncommitted) == 0 && len(l.Untracked) == 0 && len(l.Stashes) == 0 && len(l.Unpushed) == 0
+}
+
+// Summary renders one indented line per kind of work at risk.
+func (l WorkLoss) Summary() string {
+	var b strings.Builder
+	line := func(items []string, singular, plural string) {
+		if len(items) == 0 {
+			return
+		}
+		noun := plural
+		if len(items) == 1 {
+			noun = singular
+		}
+		shown := items
+		if len(shown) > 3 {
+			shown = append(shown[:3:3], "...")
+		}
+		fmt.Fprintf(&b, "  %d %s: %s\n", len(items), noun, strings.Join(shown, ", "))
+	}
+	line(l.Uncommitted, "uncommitted change", "uncommitted changes")
+	line(l.Untracked, "untracked file", "untracked files")
+	line(l.Stashes, "stash on this branch", "stashes on this branch")
+	line(l.Unpushed, "commit not on any remote", "commits not on any remote")
+	return b.String()
+}
+
+// WorkLossError is returned when a worktree is not removed because it holds
+// work that would be lost; pass --force to remove it anyway.
+type WorkLossError struct {
+	WorktreePath string
+	Loss         WorkLoss
+}
+
+func (e *WorkLossError) Error() string {
+	return fmt.Sprintf("%s has work that would be lost:\n%suse --force to remove it anyway", e.WorktreePath, e.Loss.Summary())
+}
+
+// CheckWorkLoss inspects the worktree at worktreePath, with branch checked
+// out ("" if detached), for uncommitted changes, untracked non-ignored files,
+// stashes made on branch, and commits not on any remote. Unpushed commits are
+// only looked for when the repo has a remote.
+func CheckWorkLoss(worktreePath, branch string) (WorkLoss, error) {
+	var loss WorkLoss
+
+	var buf, stderr bytes.Buffer
+	cmd := exec.Command("git", "-C", worktreePath, "status", "--porcelain")
+	cmd.Stdout = &buf
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return WorkLoss{}, fmt.Errorf("git status failed in %s: %w (%s)", worktreePath, err, strings.TrimSpace(stderr.String()))
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		if strings.HasPrefix(line, "??") {
+			loss.Untracked = append(loss.Untracked, line[3:])
+		} else {
+			loss.Uncommitted = append(loss.Uncommitted, line[3:])
+		}
+	}
+
+	if branch != "" {
+		out, _ := gitOutput(worktreePath, "stash", "list", "--format=%gd %gs")
+		for _, line := range strings.Split(out, "\n") {
+			ref, subject, ok := strings.Cut(line, " ")
+			if ok && (strings.HasPrefix(subject, "WIP on "+branch+":") || strings.HasPrefix(subject, "On "+branch+":")) {
+				loss.Stashes = append(loss.Stashes, ref)
+			}
+		}
+	}
+
+	if remotes, _ := gitOutput(worktreePath, "remote"); remotes != "" {
+		out, err := gitOutput(worktreePath, "log", "--format=%h %s", "HEAD", "--not", "--remotes")
+		if err == nil && out != "" {
+			loss.Unpushed = strings.Split(out, "\n")
+		}
+	}
+	return loss, nil
+}
+
+// CheckRemovable returns a *WorkLossError when removing the worktree at
+// worktreePath would lose work. A worktree that cannot be inspected (e.g. its
+// directory is gone) is left for git worktree remove to judge.
+func CheckRemovable(worktreePath string) error {
+	branch, _ := gitOutput(worktreePath, "symbolic-ref", "--quiet", "--short", "HEAD")
+	loss, err := CheckWorkLoss(worktreePath, branch)
+	if err != nil || loss.Empty() {
+		return nil
+	}
+	return &WorkLossError{WorktreePath: worktreePath, Loss: loss}
+}
@@ -0,0 +1,84 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckWorkLoss(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "source")
+	initRepoWithMain(t, source)
+	clone := filepath.Join(root, "clone")
+	testRunGit(t, "git", "clone", "--quiet", source, clone)
+
+	if loss, err := CheckWorkLoss(clone, "main"); err != nil || !loss.Empty() {
+		t.Fatalf("CheckWorkLoss(clean) = %+v, %v; want empty", loss, err)
+	}
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(clone, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := func(args ...string) { testRunGit(t, "git", append([]string{"-C", clone}, args...)...) }
+	write(".gitignore", "*.log\n")
+	run("add", ".gitignore")
+	run("commit", "--quiet", "-m", "ignore logs")
+	write("stash-me", "s")
+	run("add", "stash-me")
+	run("stash", "--quiet")
+	write("README", "modified")
+	write("notes.txt", "n")
+	write("debug.log", "ignored")
+
+	loss, err := CheckWorkLoss(clone, "main")
+	if err != nil {
+		t.Fatalf("CheckWorkLoss() error: %v", err)
+	}
+	want := WorkLoss{
+		Uncommitted: []string{"README"},
+		Untracked:   []string{"notes.txt"},
+		Stashes:     []string{"stash@{0}"},
+	}
+	if len(loss.Unpushed) != 1 {
+		t.Errorf("Unpushed = %v, want the one local commit", loss.Unpushed)
+	}
+	loss.Unpushed = nil
+	if !reflect.DeepEqual(loss, want) {
+		t.Errorf("CheckWorkLoss() = %+v, want %+v", loss, want)
+	}
+
+	if loss, _ := CheckWorkLoss(clone, "other"); len(loss.Stashes) != 0 {
+		t.Errorf("stashes on main reported for branch other: %v", loss.Stashes)
+	}
+
+	var lossErr *WorkLossError
+	if err := CheckRemovable(clone); !errors.As(err, &lossErr) {
+		t.Errorf("CheckRemovable() = %v, want *WorkLossError", err)
+	}
+}
+
+func TestCheckWorkLossNoRemote(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "repo")
+	initRepoWithMain(t, dir)
+	// Without a remote there is nowhere to push, so local commits are not at risk.
+	if err := CheckRemovable(dir); err != nil {
+		t.Errorf("CheckRemovable() = %v, want nil", err)
+	}
+}
+
+func TestWorkLossSummary(t *testing.T) {
+	l := WorkLoss{
+		Uncommitted: []string{"a", "b", "c", "d"},
+		Stashes:     []string{"stash@{1}"},
+	}
+	want := "  4 uncommitted changes: a, b, c, ...\n  1 stash on this branch: stash@{1}\n"
+	if got := l.Summary(); got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+}
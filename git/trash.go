@@ -0,0 +1,285 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// trashEntryFile holds a TrashEntry inside its directory under the trash dir,
+// next to the moved worktree ("worktree") and its git metadata ("admin").
+const trashEntryFile = "entry.toml"
+
+// TrashEntry records a worktree moved to the trash by Trash.
+type TrashEntry struct {
+	ID        string    `toml:"-"`          // directory name under the trash dir
+	Repo      string    `toml:"repo"`       // repo dir the worktree was removed from
+	CommonDir string    `toml:"common_dir"` // git common dir the worktree belongs to
+	Path      string    `toml:"path"`       // where the worktree lived
+	Branch    string    `toml:"branch,omitempty"`
+	SHA       string    `toml:"sha"`
+	Admin     string    `toml:"admin"` // name of its metadata dir under <common dir>/worktrees
+	Trashed   time.Time `toml:"trashed"`
+}
+
+// Name is the short name a trashed worktree is shown and restored by: its
+// branch, or its directory name when detached.
+func (e TrashEntry) Name() string {
+	if e.Branch != "" {
+		return e.Branch
+	}
+	return filepath.Base(e.Path)
+}
+
+// Trash removes a worktree the undoable way: its directory, untracked and
+// ignored files included, moves into a new entry under trashDir together with
+// its git metadata (HEAD, index, per-worktree config), which also releases
+// git's registration. The branch is kept. The worktree is chosen as for
+// Remove; flags in args are ignored.
+func (r *Repo) Trash(args []string, trashDir string) (entry TrashEntry, retErr error) {
+	_, positional := splitRemoveArgs(args)
+	path, branch, err := r.removalTarget(positional)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	sha, err := gitOutput(path, "rev-parse", "HEAD")
+	if err != nil {
+		return TrashEntry{}, fmt.Errorf("%s is not a worktree: %w", path, err)
+	}
+	adminDir, err := gitOutput(path, "rev-parse", "--path-format=absolute", "--git-dir")
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	commonDir, err := ObjectStore(path)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	if filepath.Dir(adminDir) != filepath.Join(commonDir, "worktrees") {
+		return TrashEntry{}, fmt.Errorf("%s is not a linked worktree", path)
+	}
+	if _, err := os.Stat(filepath.Join(adminDir, "locked")); err == nil {
+		return TrashEntry{}, fmt.Errorf("%s is locked; run 'gwt unlock' first", path)
+	}
+
+	entry = TrashEntry{
+		Repo:      r.Dir,
+		CommonDir: commonDir,
+		Path:      path,
+		Branch:    branch,
+		SHA:       sha,
+		Admin:     filepath.Base(adminDir),
+		Trashed:   time.Now(),
+	}
+	dir, err := newTrashDir(trashDir, entry.Trashed.Format("20060102-150405")+"-"+filepath.Base(path))
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	entry.ID = filepath.Base(dir)
+
+	var undo []func() error
+	defer func() {
+		if retErr == nil {
+			return
+		}
+		for i := len(undo) - 1; i >= 0; i-- {
+			if err := undo[i](); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: rollback step failed: %v\n", err)
+			}
+		}
+	}()
+	undo = append(undo, func() error { return os.RemoveAll(dir) })
+	if err := moveToTrash(path, filepath.Join(dir, "worktree"), trashDir); err != nil {
+		return TrashEntry{}, fmt.Errorf("failed to move %s to the trash: %w", path, err)
+	}
+	undo = append(undo, func() error { return os.Rename(filepath.Join(dir, "worktree"), path) })
+	if err := moveToTrash(adminDir, filepath.Join(dir, "admin"), trashDir); err != nil {
+		return TrashEntry{}, fmt.Errorf("failed to move worktree metadata to the trash: %w", err)
+	}
+	undo = append(undo, func() error { return os.Rename(filepath.Join(dir, "admin"), adminDir) })
+	if err := writeTrashEntry(dir, entry); err != nil {
+		return TrashEntry{}, err
+	}
+	return entry, nil
+}
+
+// moveToTrash renames src to dst under trashDir. The trash works by rename
+// alone, so a src on another filesystem is refused with a hint rather than
+// copied.
+func moveToTrash(src, dst, trashDir string) error {
+	err := os.Rename(src, dst)
+	if errors.Is(err, syscall.EXDEV) {
+		return fmt.Errorf("the trash (%s) is on a different filesystem; point XDG_DATA_HOME at the same filesystem as %s, or pass --no-trash", trashDir, src)
+	}
+	return err
+}
+
+// newTrashDir creates a fresh directory named base (or base-2, base-3, ...)
+// under trashDir.
+func newTrashDir(trashDir, base string) (string, error) {
+	if err := os.MkdirAll(trashDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	name := base
+	for i := 2; ; i++ {
+		dir := filepath.Join(trashDir, name)
+		err := os.Mkdir(dir, 0o755)
+		if err == nil {
+			return dir, nil
+		}
+		if !os.IsExist(err) {
+			return "", fmt.Errorf("failed to create trash entry: %w", err)
+		}
+		name = base + "-" + strconv.Itoa(i)
+	}
+}
+
+func writeTrashEntry(dir string, e TrashEntry) error {
+	f, err := os.Create(filepath.Join(dir, trashEntryFile))
+	if err != nil {
+		return fmt.Errorf("failed to record trash entry: %w", err)
+	}
+	if err := toml.NewEncoder(f).Encode(e); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to record trash entry: %w", err)
+	}
+	return f.Close()
+}
+
+// ListTrash returns the entries under trashDir, oldest first. Directories
+// without a readable entry are skipped.
+func ListTrash(trashDir string) ([]TrashEntry, error) {
+	dirs, err := os.ReadDir(trashDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []TrashEntry
+	for _, d := range dirs {
+		var e TrashEntry
+		if _, err := toml.DecodeFile(filepath.Join(trashDir, d.Name(), trashEntryFile), &e); err != nil {
+			continue
+		}
+		e.ID = d.Name()
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Trashed.Before(entries[j].Trashed) })
+	return entries, nil
+}
+
+// FindTrash returns the entry under trashDir named by name: its ID, or the
+// name (branch or directory name) of the most recently trashed match.
+func FindTrash(trashDir, name string) (TrashEntry, error) {
+	entries, err := ListTrash(trashDir)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.ID == name || e.Name() == name || filepath.Base(e.Path) == name {
+			return e, nil
+		}
+	}
+	return TrashEntry{}, fmt.Errorf("no trashed worktree named %q (see 'gwt trash')", name)
+}
+
+// RestoreTrash re-attaches the trashed worktree e at its original path, with
+// its index and untracked and ignored files as they were. A branch deleted in
+// the meantime is recreated at the recorded commit.
+func RestoreTrash(trashDir string, e TrashEntry) (retErr error) {
+	dir := filepath.Join(trashDir, e.ID)
+	if _, err := os.Stat(e.CommonDir); err != nil {
+		return fmt.Errorf("repository %s no longer exists", e.CommonDir)
+	}
+	if _, err := os.Lstat(e.Path); err == nil {
+		return fmt.Errorf("%s already exists; move it aside to restore", e.Path)
+	}
+	if e.Branch != "" {
+		if path, ok, _ := (&Repo{Dir: e.CommonDir}).FindWorktreeByBranch(e.Branch); ok {
+			return fmt.Errorf("branch %s is checked out at %s", e.Branch, path)
+		}
+		if !refExists(e.CommonDir, "refs/heads/"+e.Branch) {
+			if _, err := gitOutput(e.CommonDir, "branch", e.Branch, e.SHA); err != nil {
+				return fmt.Errorf("failed to recreate branch %s: %w", e.Branch, err)
+			}
+		}
+	}
+
+	worktrees := filepath.Join(e.CommonDir, "worktrees")
+	adminDir := filepath.Join(worktrees, e.Admin)
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(adminDir); os.IsNotExist(err) {
+			break
+		}
+		adminDir = filepath.Join(worktrees, e.Admin+strconv.Itoa(i))
+	}
+	if err := os.MkdirAll(worktrees, 0o755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(e.Path), 0o755); err != nil {
+		return err
+	}
+
+	var undo []func() error
+	defer func() {
+		if retErr == nil {
+			return
+		}
+		for i := len(undo) - 1; i >= 0; i-- {
+			if err := undo[i](); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: rollback step failed: %v\n", err)
+			}
+		}
+	}()
+	if err := os.Rename(filepath.Join(dir, "admin"), adminDir); err != nil {
+		return fmt.Errorf("failed to restore worktree metadata: %w", err)
+	}
+	undo = append(undo, func() error { return os.Rename(adminDir, filepath.Join(dir, "admin")) })
+	if err := os.Rename(filepath.Join(dir, "worktree"), e.Path); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", e.Path, err)
+	}
+	undo = append(undo, func() error { return os.Rename(e.Path, filepath.Join(dir, "worktree")) })
+
+	gitFile := filepath.Join(e.Path, ".git")
+	if err := os.WriteFile(filepath.Join(adminDir, "gitdir"), []byte(gitFile+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write worktree metadata: %w", err)
+	}
+	if err := os.WriteFile(gitFile, []byte("gitdir: "+adminDir+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write .git file: %w", err)
+	}
+
+	_ = os.RemoveAll(dir)
+	// Renames change ctime; refresh so the first `git status` is not a full rehash.
+	_ = exec.Command("git", "-C", e.Path, "update-index", "-q", "--refresh").Run()
+	return nil
+}
+
+// PurgeTrash permanently deletes the entries under trashDir trashed more than
+// olderThan ago (all of them when olderThan is 0) and returns them.
+func PurgeTrash(trashDir string, olderThan time.Duration) ([]TrashEntry, error) {
+	entries, err := ListTrash(trashDir)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-olderThan)
+	var purged []TrashEntry
+	for _, e := range entries {
+		if e.Trashed.After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(trashDir, e.ID)); err != nil {
+			return purged, fmt.Errorf("failed to purge %s: %w", e.ID, err)
+		}
+		purged = append(purged, e)
+	}
+	return purged, nil
+}
@@ -0,0 +1,120 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrashAndRestore(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoDir := filepath.Join(root, "repo")
+	initRepoWithMain(t, repoDir)
+	trashDir := filepath.Join(root, "trash")
+	wt := filepath.Join(root, "wt", "feat-x")
+	if err := AddWorktree(repoDir, NewBranchArgs("feat/x", "main"), wt, AddOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(wt, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".gitignore", ".env\n")
+	write(".env", "SECRET=1")
+	write("staged", "s")
+	testRunGit(t, "git", "-C", wt, "add", "staged")
+	write("notes", "untracked")
+
+	repo := &Repo{Dir: repoDir}
+	e, err := repo.Trash([]string{"--force", wt}, trashDir)
+	if err != nil {
+		t.Fatalf("Trash() error: %v", err)
+	}
+	if e.Branch != "feat/x" || e.Path != wt || e.Name() != "feat/x" {
+		t.Errorf("Trash() = %+v", e)
+	}
+	if _, err := os.Stat(wt); !os.IsNotExist(err) {
+		t.Error("worktree directory still present after Trash()")
+	}
+	if _, ok, _ := repo.FindWorktreeByBranch("feat/x"); ok {
+		t.Error("git still lists the trashed worktree")
+	}
+	if !BranchExists(repoDir, "feat/x") {
+		t.Error("Trash() deleted the branch")
+	}
+
+	// Deleting the branch meanwhile does not prevent restoring it.
+	testRunGit(t, "git", "-C", repoDir, "branch", "-D", "feat/x")
+
+	found, err := FindTrash(trashDir, "feat/x")
+	if err != nil || found.ID != e.ID {
+		t.Fatalf("FindTrash() = %+v, %v; want ID %s", found, err, e.ID)
+	}
+	if err := RestoreTrash(trashDir, found); err != nil {
+		t.Fatalf("RestoreTrash() error: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(wt, ".env")); err != nil || string(data) != "SECRET=1" {
+		t.Errorf(".env after restore = %q, %v", data, err)
+	}
+	status, err := gitOutput(wt, "status", "--porcelain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"A  staged", "?? notes"} {
+		if !strings.Contains(status, line) {
+			t.Errorf("status after restore missing %q:\n%s", line, status)
+		}
+	}
+	if path, ok, _ := repo.FindWorktreeByBranch("feat/x"); !ok || path != wt {
+		t.Errorf("restored worktree not registered: %q, %v", path, ok)
+	}
+	if entries, _ := ListTrash(trashDir); len(entries) != 0 {
+		t.Errorf("trash not emptied after restore: %v", entries)
+	}
+}
+
+func TestTrashRefusesMainWorktree(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "repo")
+	initRepoWithMain(t, dir)
+	if _, err := (&Repo{Dir: dir}).Trash([]string{dir}, t.TempDir()); err == nil {
+		t.Error("Trash(main worktree) succeeded, want error")
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	trashDir := t.TempDir()
+	now := time.Now()
+	for _, e := range []TrashEntry{
+		{ID: "old", Path: "/wt/old", Trashed: now.Add(-10 * 24 * time.Hour)},
+		{ID: "new", Path: "/wt/new", Trashed: now.Add(-time.Hour)},
+	} {
+		dir := filepath.Join(trashDir, e.ID)
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeTrashEntry(dir, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	purged, err := PurgeTrash(trashDir, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("PurgeTrash() error: %v", err)
+	}
+	if len(purged) != 1 || purged[0].ID != "old" {
+		t.Errorf("PurgeTrash(7d) = %+v, want only old", purged)
+	}
+	if entries, _ := ListTrash(trashDir); len(entries) != 1 || entries[0].ID != "new" {
+		t.Errorf("ListTrash() after purge = %+v, want only new", entries)
+	}
+	if purged, _ := PurgeTrash(trashDir, 0); len(purged) != 1 {
+		t.Errorf("PurgeTrash(0) = %+v, want the remaining entry", purged)
+	}
+}
@@ -33,49 +33,20 @@ func MainBranchRef(repoDir, mainBranch string) string {
 	return mainBranch
 }
 
-// AddWorktreeAt runs `git -C repoDir worktree add <gitArgs>`, retrying once
-// after `git fetch origin` if the ref was not found.
-func AddWorktreeAt(repoDir string, gitArgs []string) error {
-	base := []string{"-C", repoDir, "worktree", "add"}
-	var stderr bytes.Buffer
-	cmd := exec.Command("git", append(append([]string{}, base...), gitArgs...)...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = &stderr
-	cmd.Stdin = os.Stdin
-	if err := cmd.Run(); err != nil {
-		if strings.Contains(stderr.String(), "invalid reference:") {
-			fetch := exec.Command("git", "-C", repoDir, "fetch", "origin")
-			fetch.Stdout = os.Stdout
-			fetch.Stderr = os.Stderr
-			if ferr := fetch.Run(); ferr != nil {
-				return fmt.Errorf("git fetch failed: %w", ferr)
-			}
-			retry := exec.Command("git", append(append([]string{}, base...), gitArgs...)...)
-			retry.Stdout = os.Stdout
-			retry.Stderr = os.Stderr
-			retry.Stdin = os.Stdin
-			if rerr := retry.Run(); rerr != nil {
-				return fmt.Errorf("git worktree add failed: %w", rerr)
-			}
-			return nil
-		}
-		_, _ = os.Stderr.Write(stderr.Bytes())
-		return fmt.Errorf("git worktree add failed: %w", err)
-	}
-	return nil
-}
-
 // MemberRemoval reports the outcome of removing one workspace member worktree.
 type MemberRemoval struct {
-	Freed      disk.Result // reclaimed space (zero if removal failed)
-	BranchKept string      // branch left undeleted because it was not merged
-	Err        error       // worktree-removal error; nil on success
+	Freed      disk.Result    // reclaimed space (zero if removal failed)
+	BranchKept string         // branch left undeleted because it was not merged
+	Remote     RemoteDeletion // outcome of deleting the remote branch, with opts.Remote
+	Err        error          // worktree-removal error; nil on success
 }
 
-// RemoveMemberWorktree removes one member's worktree and, unless keepBranch,
-// safely deletes its branch. It returns structured results rather than
-// printing, so the caller can aggregate across members.
-func RemoveMemberWorktree(repoDir, worktreePath string, keepBranch, force bool) MemberRemoval {
+// RemoveMemberWorktree removes one member's worktree and, unless
+// opts.KeepBranch, safely deletes its branch, and its remote branch with
+// opts.Remote. Unless opts.Force, a worktree holding work that would be lost
+// is refused with a *WorkLossError. It returns structured results
+// rather than printing, so the caller can aggregate across members.
+func RemoveMemberWorktree(repoDir, worktreePath string, opts RemoveOptions) MemberRemoval {
 	var branch string
 	var buf bytes.Buffer
 	bc := exec.Command("git", "-C", worktreePath, "rev-parse", "--abbrev-ref", "HEAD")
@@ -86,10 +57,16 @@ func RemoveMemberWorktree(repoDir, worktreePath string, keepBranch, force bool)
 		}
 	}
 
+	if !opts.Force {
+		if err := CheckRemovable(worktreePath); err != nil {
+			return MemberRemoval{Err: err}
+		}
+	}
+
 	freed, _ := disk.Size(worktreePath) // best-effort, before removal
 
 	args := []string{"-C", repoDir, "worktree", "remove"}
-	if force {
+	if opts.Force {
 		args = append(args, "--force")
 	}
 	args = append(args, worktreePath)
@@ -100,15 +77,24 @@ func RemoveMemberWorktree(repoDir, worktreePath string, keepBranch, force bool)
 	}
 
 	mr := MemberRemoval{Freed: freed}
-	if !keepBranch && branch != "" {
-		del := exec.Command("git", "-C", repoDir, "branch", "-d", branch)
-		if err := del.Run(); err != nil {
+	if opts.Remote && branch != "" {
+		mr.Remote = DeleteRemoteBranch(repoDir, branch, opts.ForceRemote)
+	}
+	if !opts.KeepBranch && branch != "" {
+		if err := DeleteBranch(repoDir, branch); err != nil {
 			mr.BranchKept = branch // not fully merged; caller reports it
 		}
 	}
 	return mr
 }
 
+// DeleteBranch deletes branch with `git branch -d`, which refuses a branch
+// that is not fully merged.
+func DeleteBranch(repoDir, branch string) error {
+	_, err := gitOutput(repoDir, "branch", "-d", branch)
+	return err
+}
+
 // RunSetup runs a shell command in dir, streaming stdio.
 // command is trusted configuration from the user's own config.toml (same trust
 // level as a git hook) — NOT untrusted input; no sanitization is needed.
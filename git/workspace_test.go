@@ -1,6 +1,7 @@
 package git
 
 import (
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -52,15 +53,14 @@ func TestMainBranchRef(t *testing.T) {
 	}
 }
 
-func TestAddWorktreeAt(t *testing.T) {
+func TestAddWorktree(t *testing.T) {
 	root := t.TempDir()
 	repo := filepath.Join(root, "repo")
 	initRepoWithMain(t, repo)
 
 	wt := filepath.Join(root, "wt", "feat")
-	args := []string{"-b", "feat/x", wt, "main"}
-	if err := AddWorktreeAt(repo, args); err != nil {
-		t.Fatalf("AddWorktreeAt error: %v", err)
+	if err := AddWorktree(repo, NewBranchArgs("feat/x", "main"), wt, AddOptions{}); err != nil {
+		t.Fatalf("AddWorktree error: %v", err)
 	}
 	if _, err := os.Stat(filepath.Join(wt, "README")); err != nil {
 		t.Errorf("worktree not created: %v", err)
@@ -72,11 +72,11 @@ func TestRemoveMemberWorktree(t *testing.T) {
 	repo := filepath.Join(root, "repo")
 	initRepoWithMain(t, repo)
 	wt := filepath.Join(root, "wt", "feat")
-	if err := AddWorktreeAt(repo, []string{"-b", "feat/x", wt, "main"}); err != nil {
+	if err := AddWorktree(repo, NewBranchArgs("feat/x", "main"), wt, AddOptions{}); err != nil {
 		t.Fatal(err)
 	}
 
-	if mr := RemoveMemberWorktree(repo, wt, false, false); mr.Err != nil {
+	if mr := RemoveMemberWorktree(repo, wt, RemoveOptions{}); mr.Err != nil {
 		t.Fatalf("RemoveMemberWorktree error: %v", mr.Err)
 	}
 	if _, err := os.Stat(wt); !os.IsNotExist(err) {
@@ -93,10 +93,10 @@ func TestRemoveMemberWorktreeKeepBranch(t *testing.T) {
 	repo := filepath.Join(root, "repo")
 	initRepoWithMain(t, repo)
 	wt := filepath.Join(root, "wt", "feat")
-	if err := AddWorktreeAt(repo, []string{"-b", "feat/x", wt, "main"}); err != nil {
+	if err := AddWorktree(repo, NewBranchArgs("feat/x", "main"), wt, AddOptions{}); err != nil {
 		t.Fatal(err)
 	}
-	if mr := RemoveMemberWorktree(repo, wt, true, false); mr.Err != nil {
+	if mr := RemoveMemberWorktree(repo, wt, RemoveOptions{KeepBranch: true}); mr.Err != nil {
 		t.Fatal(mr.Err)
 	}
 	if !BranchExists(repo, "feat/x") {
@@ -104,6 +104,31 @@ func TestRemoveMemberWorktreeKeepBranch(t *testing.T) {
 	}
 }
 
+func TestRemoveMemberWorktreeRefusesLoss(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	initRepoWithMain(t, repo)
+	wt := filepath.Join(root, "wt", "feat")
+	if err := AddWorktree(repo, NewBranchArgs("feat/x", "main"), wt, AddOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wt, "notes"), []byte("n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mr := RemoveMemberWorktree(repo, wt, RemoveOptions{})
+	var lossErr *WorkLossError
+	if !errors.As(mr.Err, &lossErr) {
+		t.Fatalf("RemoveMemberWorktree error = %v, want *WorkLossError", mr.Err)
+	}
+	if _, err := os.Stat(wt); err != nil {
+		t.Errorf("worktree removed despite untracked file: %v", err)
+	}
+	if mr := RemoveMemberWorktree(repo, wt, RemoveOptions{Force: true}); mr.Err != nil {
+		t.Fatalf("RemoveMemberWorktree(force) error: %v", mr.Err)
+	}
+}
+
 func TestRunSetup(t *testing.T) {
 	dir := t.TempDir()
 	if err := RunSetup("touch marker", dir); err != nil {
@@ -0,0 +1,101 @@
+package hook
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "identical",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			old:  "a\nb\nc\n",
+			new:  "a\nB\nc\n",
+			want: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "from empty",
+			old:  "",
+			new:  "a\nb\n",
+			want: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "distant changes split into hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			want: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{
+			name: "nearby changes share a hunk",
+			old:  "1\n2\n3\n4\n5\n6\n",
+			new:  "one\n2\n3\n4\n5\nsix\n",
+			want: "--- old\n+++ new\n@@ -1,6 +1,6 @@\n-1\n+one\n 2\n 3\n 4\n 5\n-6\n+six\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unifiedDiff("old", "new", tt.old, tt.new)
+			if got != tt.want {
+				t.Errorf("unifiedDiff() mismatch\n--- got ---\n%s\n--- want ---\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	data := HookData{PackageManager: "pnpm"}
+	content, err := Generate(data)
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	t.Run("no installed hook", func(t *testing.T) {
+		got, err := Diff(filepath.Join(t.TempDir(), "hooks"), content)
+		if err != nil {
+			t.Fatalf("Diff() error: %v", err)
+		}
+		if !strings.HasPrefix(got, "--- /dev/null\n") {
+			t.Errorf("Diff() should diff against /dev/null\n---\n%s", got)
+		}
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		hooksDir := t.TempDir()
+		if err := Install(hooksDir, data, false); err != nil {
+			t.Fatalf("Install() error: %v", err)
+		}
+		got, err := Diff(hooksDir, content)
+		if err != nil {
+			t.Fatalf("Diff() error: %v", err)
+		}
+		if got != "" {
+			t.Errorf("Diff() = %q, want empty", got)
+		}
+	})
+
+	t.Run("hand-edited hook", func(t *testing.T) {
+		hooksDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(hooksDir, "post-checkout"), []byte("#!/bin/bash\necho custom\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Diff(hooksDir, content)
+		if err != nil {
+			t.Fatalf("Diff() error: %v", err)
+		}
+		if !strings.Contains(got, "-echo custom") || !strings.Contains(got, "+        if pnpm install; then") {
+			t.Errorf("Diff() missing expected lines\n---\n%s", got)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package hook
 import (
 	"bytes"
 	"embed"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -18,6 +19,22 @@ type HookData struct {
 	CopyFiles      []string
 	VersionManager string
 	PackageManager string
+	// Version is the gwt version recorded in the provenance header. It does
+	// not affect the hook body.
+	Version string
+	// Chained makes the hook run the preserved original (ChainedHook) after
+	// its own work. Install and Check derive it from hooksDir.
+	Chained bool
+}
+
+// ChainedHook is the file a pre-existing hand-written post-checkout hook is
+// moved to when gwt's hook replaces it. The generated hook runs it, and
+// Uninstall moves it back.
+const ChainedHook = "post-checkout.pre-gwt"
+
+// ChainedName exposes ChainedHook to the template.
+func (d HookData) ChainedName() string {
+	return ChainedHook
 }
 
 func (d HookData) BuildCommand() string {
@@ -35,7 +52,26 @@ func shellEscape(s string) string {
 	return strings.ReplaceAll(s, "'", "'\\''")
 }
 
+// Generate renders the post-checkout hook for data, with a provenance header
+// recording the gwt version, the inputs hash, and a checksum of the body.
 func Generate(data HookData) (string, error) {
+	body, err := render(data)
+	if err != nil {
+		return "", err
+	}
+	version := data.Version
+	if version == "" {
+		version = unknownVersion
+	}
+	return addHeader(body, Provenance{
+		Version:    version,
+		InputsHash: InputsHash(data),
+		Checksum:   digest(body),
+	}), nil
+}
+
+// render executes the hook template, without the provenance header.
+func render(data HookData) (string, error) {
 	funcMap := template.FuncMap{"shellEscape": shellEscape}
 	tmpl, err := template.New("post-checkout.sh.tmpl").Funcs(funcMap).ParseFS(templates, "templates/post-checkout.sh.tmpl")
 	if err != nil {
@@ -49,6 +85,43 @@ func Generate(data HookData) (string, error) {
 	return buf.String(), nil
 }
 
+// legacyPrefix is how every hook generated by a gwt predating provenance
+// headers begins.
+const legacyPrefix = "#!/bin/bash\n\nif [[ \"$1\" == \"0000000000000000000000000000000000000000\" ]]; then\n"
+
+// generatedByGwt reports whether hook content was written by gwt: it carries
+// a provenance header, or it has the signature of the template gwt used
+// before headers. Such a hook is overwritten rather than chained.
+func generatedByGwt(content string) bool {
+	if _, _, ok := ParseHeader(content); ok {
+		return true
+	}
+	return strings.HasPrefix(content, legacyPrefix)
+}
+
+// chainedOriginal reports whether hooksDir holds a preserved hand-written
+// hook (ChainedHook). A gwt hook found there is not one: it was moved aside
+// by a gwt that did not recognise its predecessor's hooks.
+func chainedOriginal(hooksDir string) bool {
+	content, err := os.ReadFile(filepath.Join(hooksDir, ChainedHook))
+	return err == nil && !generatedByGwt(string(content))
+}
+
+// Planned returns data as Install would render it into hooksDir: chained to
+// the preserved original hook when one exists or when installing would
+// preserve the currently installed hand-written hook.
+func Planned(hooksDir string, data HookData) HookData {
+	if chainedOriginal(hooksDir) {
+		data.Chained = true
+		return data
+	}
+	content, err := os.ReadFile(filepath.Join(hooksDir, "post-checkout"))
+	if err == nil {
+		data.Chained = !generatedByGwt(string(content))
+	}
+	return data
+}
+
 func Install(hooksDir string, data HookData, force bool) error {
 	hookPath := filepath.Join(hooksDir, "post-checkout")
 
@@ -58,6 +131,7 @@ func Install(hooksDir string, data HookData, force bool) error {
 		}
 	}
 
+	data = Planned(hooksDir, data)
 	content, err := Generate(data)
 	if err != nil {
 		return err
@@ -67,9 +141,66 @@ func Install(hooksDir string, data HookData, force bool) error {
 		return fmt.Errorf("failed to create hooks directory: %w", err)
 	}
 
+	// Preserve a hand-written hook so the generated one can chain to it; one
+	// an older gwt wrote is simply replaced, and dropped if it was chained.
+	chainedPath := filepath.Join(hooksDir, ChainedHook)
+	if _, err := os.Stat(chainedPath); err == nil && !chainedOriginal(hooksDir) {
+		if err := os.Remove(chainedPath); err != nil {
+			return fmt.Errorf("failed to remove chained gwt hook: %w", err)
+		}
+	}
+	if existing, err := os.ReadFile(hookPath); err == nil {
+		if !generatedByGwt(string(existing)) {
+			if _, err := os.Stat(chainedPath); os.IsNotExist(err) {
+				if err := os.Rename(hookPath, chainedPath); err != nil {
+					return fmt.Errorf("failed to preserve existing hook: %w", err)
+				}
+			}
+		}
+	}
+
 	if err := os.WriteFile(hookPath, []byte(content), 0o755); err != nil {
 		return fmt.Errorf("failed to write hook: %w", err)
 	}
 
 	return nil
 }
+
+// ErrNotInstalled is returned by Uninstall when there is no post-checkout
+// hook to remove.
+var ErrNotInstalled = errors.New("no post-checkout hook installed")
+
+// Uninstall removes the gwt-generated post-checkout hook from hooksDir,
+// moving a preserved original (ChainedHook) back into place. It refuses to
+// remove a hook gwt did not generate. restored reports whether an original
+// hook was put back.
+func Uninstall(hooksDir string) (restored bool, err error) {
+	hookPath := filepath.Join(hooksDir, "post-checkout")
+	content, err := os.ReadFile(hookPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, fmt.Errorf("%w at %s", ErrNotInstalled, hookPath)
+		}
+		return false, fmt.Errorf("failed to read hook: %w", err)
+	}
+	if !generatedByGwt(string(content)) {
+		return false, fmt.Errorf("post-checkout hook at %s was not generated by gwt; refusing to remove it", hookPath)
+	}
+	if err := os.Remove(hookPath); err != nil {
+		return false, fmt.Errorf("failed to remove hook: %w", err)
+	}
+	chainedPath := filepath.Join(hooksDir, ChainedHook)
+	if _, err := os.Stat(chainedPath); err != nil {
+		return false, nil
+	}
+	if !chainedOriginal(hooksDir) {
+		if err := os.Remove(chainedPath); err != nil {
+			return false, fmt.Errorf("failed to remove chained gwt hook: %w", err)
+		}
+		return false, nil
+	}
+	if err := os.Rename(chainedPath, hookPath); err != nil {
+		return false, fmt.Errorf("failed to restore original hook: %w", err)
+	}
+	return true, nil
+}
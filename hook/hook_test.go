@@ -2,6 +2,7 @@ package hook
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -269,8 +270,12 @@ fi
 			if err != nil {
 				t.Fatalf("Generate() error: %v", err)
 			}
-			if got != tt.want {
-				t.Errorf("output mismatch\n--- got ---\n%s\n--- want ---\n%s", got, tt.want)
+			_, body, ok := ParseHeader(got)
+			if !ok {
+				t.Fatalf("output has no provenance header\n---\n%s", got)
+			}
+			if body != tt.want {
+				t.Errorf("output mismatch\n--- got ---\n%s\n--- want ---\n%s", body, tt.want)
 			}
 			assertValidBash(t, got)
 		})
@@ -375,4 +380,150 @@ func TestInstall(t *testing.T) {
 			t.Error("file missing new content after force overwrite")
 		}
 	})
+	t.Run("force preserves a hand-written hook and chains to it", func(t *testing.T) {
+		hooksDir := t.TempDir()
+		original := "#!/bin/sh\necho original\n"
+		if err := os.WriteFile(filepath.Join(hooksDir, "post-checkout"), []byte(original), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := Install(hooksDir, data, true); err != nil {
+			t.Fatalf("force Install() error: %v", err)
+		}
+
+		preserved, err := os.ReadFile(filepath.Join(hooksDir, ChainedHook))
+		if err != nil || string(preserved) != original {
+			t.Fatalf("original not preserved at %s: %q, %v", ChainedHook, preserved, err)
+		}
+		content, err := os.ReadFile(filepath.Join(hooksDir, "post-checkout"))
+		if err != nil {
+			t.Fatalf("reading hook: %v", err)
+		}
+		if !strings.Contains(string(content), ChainedHook) {
+			t.Errorf("hook does not chain to %s\n---\n%s", ChainedHook, content)
+		}
+		assertValidBash(t, string(content))
+		if state, _, err := Check(hooksDir, data); err != nil || state != StateCurrent {
+			t.Errorf("Check() = %v, %v; want StateCurrent", state, err)
+		}
+	})
+
+	t.Run("force replaces a hook from a gwt predating headers", func(t *testing.T) {
+		hooksDir := t.TempDir()
+		legacy, err := render(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(hooksDir, "post-checkout"), []byte(legacy), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := Install(hooksDir, data, true); err != nil {
+			t.Fatalf("Install() error: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(hooksDir, ChainedHook)); !os.IsNotExist(err) {
+			t.Errorf("legacy gwt hook was chained as %s, want it replaced", ChainedHook)
+		}
+		if state, _, err := Check(hooksDir, data); err != nil || state != StateCurrent {
+			t.Errorf("Check() = %v, %v; want StateCurrent", state, err)
+		}
+	})
+
+	t.Run("drops a gwt hook chained by mistake", func(t *testing.T) {
+		hooksDir := t.TempDir()
+		legacy, err := render(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(hooksDir, ChainedHook), []byte(legacy), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if Planned(hooksDir, data).Chained {
+			t.Error("Planned() chains to a gwt hook")
+		}
+		if err := Install(hooksDir, data, true); err != nil {
+			t.Fatalf("Install() error: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(hooksDir, ChainedHook)); !os.IsNotExist(err) {
+			t.Errorf("chained gwt hook still present at %s", ChainedHook)
+		}
+	})
+}
+
+func TestUninstall(t *testing.T) {
+	data := HookData{PackageManager: "npm"}
+
+	t.Run("removes a generated hook", func(t *testing.T) {
+		hooksDir := t.TempDir()
+		if err := Install(hooksDir, data, false); err != nil {
+			t.Fatalf("Install() error: %v", err)
+		}
+		restored, err := Uninstall(hooksDir)
+		if err != nil {
+			t.Fatalf("Uninstall() error: %v", err)
+		}
+		if restored {
+			t.Error("restored = true, want false")
+		}
+		if _, err := os.Stat(filepath.Join(hooksDir, "post-checkout")); !os.IsNotExist(err) {
+			t.Error("hook still present after Uninstall")
+		}
+	})
+
+	t.Run("restores the chained original", func(t *testing.T) {
+		hooksDir := t.TempDir()
+		original := "#!/bin/sh\necho original\n"
+		if err := os.WriteFile(filepath.Join(hooksDir, "post-checkout"), []byte(original), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := Install(hooksDir, data, true); err != nil {
+			t.Fatalf("Install() error: %v", err)
+		}
+		restored, err := Uninstall(hooksDir)
+		if err != nil {
+			t.Fatalf("Uninstall() error: %v", err)
+		}
+		if !restored {
+			t.Error("restored = false, want true")
+		}
+		got, err := os.ReadFile(filepath.Join(hooksDir, "post-checkout"))
+		if err != nil || string(got) != original {
+			t.Errorf("hook = %q, %v; want original restored", got, err)
+		}
+		if _, err := os.Stat(filepath.Join(hooksDir, ChainedHook)); !os.IsNotExist(err) {
+			t.Error("chained copy still present after restore")
+		}
+	})
+
+	t.Run("removes a hook from a gwt predating headers", func(t *testing.T) {
+		hooksDir := t.TempDir()
+		legacy, err := render(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(hooksDir, "post-checkout"), []byte(legacy), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Uninstall(hooksDir); err != nil {
+			t.Fatalf("Uninstall() error: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(hooksDir, "post-checkout")); !os.IsNotExist(err) {
+			t.Error("hook still present after Uninstall")
+		}
+	})
+
+	t.Run("refuses a hand-written hook", func(t *testing.T) {
+		hooksDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(hooksDir, "post-checkout"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Uninstall(hooksDir); err == nil {
+			t.Error("Uninstall() of a hand-written hook succeeded, want error")
+		}
+	})
+
+	t.Run("missing hook", func(t *testing.T) {
+		if _, err := Uninstall(t.TempDir()); !errors.Is(err, ErrNotInstalled) {
+			t.Errorf("Uninstall() with no hook error = %v, want ErrNotInstalled", err)
+		}
+	})
 }
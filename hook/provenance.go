@@ -0,0 +1,126 @@
+package hook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Provenance header lines embedded after the shebang of every generated hook.
+// The checksum covers the hook with the header removed, so it is stable
+// regardless of which gwt version wrote it.
+const (
+	headerBanner   = "# Generated by gwt. Do not edit; regenerate with 'gwt init --upgrade'."
+	versionKey     = "# gwt-version: "
+	inputsKey      = "# gwt-inputs: "
+	checksumKey    = "# gwt-checksum: "
+	unknownVersion = "unknown"
+)
+
+// Provenance is the metadata recorded in a generated hook's header.
+type Provenance struct {
+	Version    string // gwt version that wrote the hook
+	InputsHash string // InputsHash of the HookData it was generated from
+	Checksum   string // checksum of the hook body (header excluded)
+}
+
+// State classifies an installed hook against what gwt would generate now.
+type State int
+
+const (
+	StateMissing State = iota // no post-checkout hook installed
+	StateForeign              // no gwt header: hand-written, or from a gwt predating headers
+	StateEdited               // gwt header present, but the body no longer matches its checksum
+	StateStale                // unmodified gwt hook that differs from the current template/inputs
+	StateCurrent              // identical to what Generate produces now
+)
+
+// digest returns a short hex sha256 of s.
+func digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
+
+// InputsHash hashes the fields of d that determine the hook body. Version is
+// excluded so upgrading gwt alone does not change it.
+func InputsHash(d HookData) string {
+	parts := []string{d.BasePath, strings.Join(d.CopyFiles, "\x1f"), d.VersionManager, d.PackageManager, fmt.Sprint(d.Chained)}
+	return digest(strings.Join(parts, "\x00"))
+}
+
+// addHeader inserts the provenance header after the shebang line of body.
+func addHeader(body string, p Provenance) string {
+	shebang, rest, _ := strings.Cut(body, "\n")
+	var b strings.Builder
+	b.WriteString(shebang + "\n")
+	b.WriteString(headerBanner + "\n")
+	b.WriteString(versionKey + p.Version + "\n")
+	b.WriteString(inputsKey + p.InputsHash + "\n")
+	b.WriteString(checksumKey + p.Checksum + "\n")
+	b.WriteString(rest)
+	return b.String()
+}
+
+// parseHeaderLine records line into p if it is a provenance header line,
+// reporting whether it was one.
+func parseHeaderLine(line string, p *Provenance) bool {
+	switch {
+	case line == headerBanner:
+	case strings.HasPrefix(line, versionKey):
+		p.Version = strings.TrimPrefix(line, versionKey)
+	case strings.HasPrefix(line, inputsKey):
+		p.InputsHash = strings.TrimPrefix(line, inputsKey)
+	case strings.HasPrefix(line, checksumKey):
+		p.Checksum = strings.TrimPrefix(line, checksumKey)
+	default:
+		return false
+	}
+	return true
+}
+
+// ParseHeader extracts the provenance header from hook content, returning it
+// along with the body (the content with the header lines removed). ok is false
+// when the content carries no gwt header.
+func ParseHeader(content string) (p Provenance, body string, ok bool) {
+	lines := strings.SplitAfter(content, "\n")
+	i := 1
+	for i < len(lines) && parseHeaderLine(strings.TrimSuffix(lines[i], "\n"), &p) {
+		i++
+	}
+	if p.Checksum == "" {
+		return Provenance{}, content, false
+	}
+	return p, lines[0] + strings.Join(lines[i:], ""), true
+}
+
+// Check reads the post-checkout hook in hooksDir and classifies it against the
+// hook Generate would produce from data. The returned Provenance is the
+// installed hook's header (zero unless the hook carries one).
+func Check(hooksDir string, data HookData) (State, Provenance, error) {
+	content, err := os.ReadFile(filepath.Join(hooksDir, "post-checkout"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return StateMissing, Provenance{}, nil
+		}
+		return 0, Provenance{}, fmt.Errorf("failed to read hook: %w", err)
+	}
+	p, body, ok := ParseHeader(string(content))
+	if !ok {
+		return StateForeign, Provenance{}, nil
+	}
+	if digest(body) != p.Checksum {
+		return StateEdited, p, nil
+	}
+	data.Chained = chainedOriginal(hooksDir)
+	want, err := render(data)
+	if err != nil {
+		return 0, p, err
+	}
+	if body != want {
+		return StateStale, p, nil
+	}
+	return StateCurrent, p, nil
+}
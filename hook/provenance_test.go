@@ -0,0 +1,119 @@
+package hook
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateHeader(t *testing.T) {
+	data := HookData{PackageManager: "pnpm", Version: "v1.2.3"}
+	got, err := Generate(data)
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if !strings.HasPrefix(got, "#!/bin/bash\n"+headerBanner+"\n") {
+		t.Errorf("header not directly after shebang\n---\n%s", got)
+	}
+	p, body, ok := ParseHeader(got)
+	if !ok {
+		t.Fatal("ParseHeader() ok = false, want true")
+	}
+	if p.Version != "v1.2.3" {
+		t.Errorf("Version = %q, want v1.2.3", p.Version)
+	}
+	if p.InputsHash != InputsHash(data) {
+		t.Errorf("InputsHash = %q, want %q", p.InputsHash, InputsHash(data))
+	}
+	if p.Checksum != digest(body) {
+		t.Errorf("Checksum = %q, want digest of body %q", p.Checksum, digest(body))
+	}
+	if strings.Contains(body, "gwt-") {
+		t.Errorf("body still contains header lines\n---\n%s", body)
+	}
+}
+
+func TestInputsHash(t *testing.T) {
+	base := HookData{BasePath: "/repo", CopyFiles: []string{".env"}, PackageManager: "pnpm"}
+	if InputsHash(base) != InputsHash(HookData{BasePath: "/repo", CopyFiles: []string{".env"}, PackageManager: "pnpm", Version: "v9"}) {
+		t.Error("InputsHash should ignore Version")
+	}
+	for _, other := range []HookData{
+		{BasePath: "/other", CopyFiles: []string{".env"}, PackageManager: "pnpm"},
+		{BasePath: "/repo", CopyFiles: []string{".env", "x"}, PackageManager: "pnpm"},
+		{BasePath: "/repo", CopyFiles: []string{".env"}, PackageManager: "npm"},
+		{BasePath: "/repo", CopyFiles: []string{".env"}, PackageManager: "pnpm", VersionManager: "mise"},
+	} {
+		if InputsHash(base) == InputsHash(other) {
+			t.Errorf("InputsHash(%+v) collides with base", other)
+		}
+	}
+}
+
+func TestParseHeaderForeign(t *testing.T) {
+	content := "#!/bin/sh\n# my own hook\necho hi\n"
+	_, body, ok := ParseHeader(content)
+	if ok {
+		t.Error("ParseHeader() ok = true for a hand-written hook")
+	}
+	if body != content {
+		t.Errorf("body = %q, want content unchanged", body)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	data := HookData{BasePath: "/repo", PackageManager: "pnpm", Version: "v1"}
+
+	install := func(t *testing.T, content string) string {
+		t.Helper()
+		hooksDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(hooksDir, "post-checkout"), []byte(content), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return hooksDir
+	}
+	current, err := Generate(data)
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	older, err := Generate(HookData{BasePath: "/repo", PackageManager: "npm", Version: "v0"})
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		hooksDir string
+		want     State
+	}{
+		{"missing", t.TempDir(), StateMissing},
+		{"foreign", install(t, "#!/bin/sh\necho hi\n"), StateForeign},
+		{"edited", install(t, strings.Replace(current, "pnpm install", "pnpm install --frozen-lockfile", 1)), StateEdited},
+		{"stale", install(t, older), StateStale},
+		{"current", install(t, current), StateCurrent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := Check(tt.hooksDir, data)
+			if err != nil {
+				t.Fatalf("Check() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("newer gwt with same template is current", func(t *testing.T) {
+		newer := data
+		newer.Version = "v2"
+		got, p, err := Check(install(t, current), newer)
+		if err != nil {
+			t.Fatalf("Check() error: %v", err)
+		}
+		if got != StateCurrent || p.Version != "v1" {
+			t.Errorf("Check() = %v (version %q), want StateCurrent (v1)", got, p.Version)
+		}
+	})
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nicwestvold/gwt/detect"
+	"github.com/nicwestvold/gwt/git"
+	"github.com/nicwestvold/gwt/hook"
+)
+
+// errAborted is returned when the user ends input (Ctrl-D) mid-prompt.
+var errAborted = errors.New("aborted")
+
+// prompter asks questions on out and reads answers line by line from in.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask prints question with def as the pre-filled answer and returns the
+// trimmed reply, or def when the reply is empty.
+func (p prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		fmt.Fprintln(p.out)
+		return "", errAborted
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// confirm asks a yes/no question, returning def for an empty reply.
+func (p prompter) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		answer, err := p.ask(question+" ["+hint+"]", "")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(p.out, "please answer y or n")
+	}
+}
+
+// choose asks for one of the valid values (or "none"), pre-filled with def.
+// Returns "" for none.
+func (p prompter) choose(question, def string, valid map[string]bool) (string, error) {
+	options := make([]string, 0, len(valid))
+	for v := range valid {
+		options = append(options, v)
+	}
+	sort.Strings(options)
+	if def == "" {
+		def = "none"
+	}
+	for {
+		answer, err := p.ask(fmt.Sprintf("%s (%s, or none)", question, strings.Join(options, ", ")), def)
+		if err != nil {
+			return "", err
+		}
+		if answer == "none" {
+			return "", nil
+		}
+		if valid[answer] {
+			return answer, nil
+		}
+		fmt.Fprintf(p.out, "%q is not one of: %s, none\n", answer, strings.Join(options, ", "))
+	}
+}
+
+// parseSelection parses a list selection such as "1,3", "2-4 6", "all", or
+// "" (none) against n numbered items, returning sorted 0-based indexes.
+func parseSelection(input string, n int) ([]int, error) {
+	input = strings.TrimSpace(input)
+	switch input {
+	case "", "none":
+		return nil, nil
+	case "all":
+		out := make([]int, n)
+		for i := range out {
+			out[i] = i
+		}
+		return out, nil
+	}
+	seen := map[int]bool{}
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == ' ' }) {
+		lo, hi, isRange := strings.Cut(field, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection %q", field)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(hi); err != nil {
+				return nil, fmt.Errorf("invalid selection %q", field)
+			}
+		}
+		if start < 1 || end > n || start > end {
+			return nil, fmt.Errorf("selection %q is out of range 1-%d", field, n)
+		}
+		for i := start; i <= end; i++ {
+			seen[i-1] = true
+		}
+	}
+	out := make([]int, 0, len(seen))
+	for i := range seen {
+		out = append(out, i)
+	}
+	sort.Ints(out)
+	return out, nil
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal.
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// runInitWizard walks the user through `gwt init`: the main branch, the
+// detected managers, which gitignored files to copy, and a preview of the
+// hook, then registers the repo and installs the hook through the same
+// registerRepo/setupHook path the flags use.
+func runInitWizard(p prompter, repo *git.Repo) error {
+	mainBranch, err := p.ask("Main branch", git.ResolveMainBranch(repo.Dir, ""))
+	if err != nil {
+		return err
+	}
+	opts := hookOptions{mainBranch: mainBranch}
+
+	src := fileSourceFor(repo, mainBranch)
+	res := detect.Detect(src, exec.LookPath)
+	fmt.Fprintf(p.out, "\nDetection (in %s):\n", describeSource(src))
+	fmt.Fprintf(p.out, "  version manager: %s\n", describeManager("", false, res.VersionManager, res.VersionManagerSignal))
+	fmt.Fprintf(p.out, "  package manager: %s\n", describeManager("", false, res.PackageManager, res.PackageManagerSignal))
+	if opts.versionManager, err = p.choose("Version manager", res.VersionManager, validVersionManagers); err != nil {
+		return err
+	}
+	if opts.packageManager, err = p.choose("Package manager", res.PackageManager, validPackageManagers); err != nil {
+		return err
+	}
+
+	basePath := repoBasePath(repo, mainBranch)
+	if fi, statErr := os.Stat(basePath); statErr == nil && fi.IsDir() {
+		candidates, err := git.IgnoredFiles(basePath)
+		if err != nil {
+			return err
+		}
+		if len(candidates) > 0 {
+			fmt.Fprintf(p.out, "\nGitignored files in %s that new worktrees won't have:\n", basePath)
+			for i, c := range candidates {
+				fmt.Fprintf(p.out, "  %d) %s\n", i+1, c)
+			}
+			for {
+				answer, err := p.ask("Copy which into new worktrees? (e.g. 1,3 or 2-4, 'all', empty for none)", "")
+				if err != nil {
+					return err
+				}
+				picked, selErr := parseSelection(answer, len(candidates))
+				if selErr != nil {
+					fmt.Fprintln(p.out, selErr)
+					continue
+				}
+				for _, i := range picked {
+					opts.copyFiles = append(opts.copyFiles, strings.TrimSuffix(candidates[i], "/"))
+				}
+				break
+			}
+		}
+	} else {
+		fmt.Fprintf(p.out, "\n%s is not checked out, so there are no local files to offer for copying.\n", basePath)
+	}
+
+	if !hookHasWork(opts) {
+		fmt.Fprintln(p.out, "\nNothing for a hook to do; registering the repo without one.")
+		return registerRepo(repo, opts)
+	}
+
+	hooksDir, err := repo.HooksDir()
+	if err != nil {
+		return err
+	}
+	content, err := hook.Generate(hook.Planned(hooksDir, hookDataFor(repo, opts)))
+	if err != nil {
+		return err
+	}
+	hookPath := filepath.Join(hooksDir, "post-checkout")
+	question := "Install this hook?"
+	if _, statErr := os.Stat(hookPath); statErr == nil {
+		diff, err := hook.Diff(hooksDir, content)
+		if err != nil {
+			return err
+		}
+		if diff == "" {
+			fmt.Fprintln(p.out, "\nInstalled hook is already up to date.")
+			return registerRepo(repo, opts)
+		}
+		fmt.Fprintf(p.out, "\nChanges to %s:\n%s", hookPath, diff)
+		question = "Overwrite the installed hook?"
+	} else {
+		fmt.Fprintf(p.out, "\nHook (%s):\n%s", hookPath, content)
+	}
+	ok, err := p.confirm(question, true)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintln(p.out, "Nothing written.")
+		return nil
+	}
+
+	if err := registerRepo(repo, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to register repo in config: %v\n", err)
+	}
+	opts.force = true
+	return setupHook(repo, opts)
+}
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"runtime"
 	"runtime/debug"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/nicwestvold/gwt/config"
 	"github.com/nicwestvold/gwt/detect"
@@ -63,14 +71,23 @@ Aliases:
 
 Additional commands:
   clone      Clone a repo into a bare-repo worktree structure
+  convert    Convert a regular clone into the bare-repo worktree layout
+  exec       Run a command in every worktree
   init       Generate a post-checkout hook for worktree setup
+  jump       Switch to the best matching worktree of any registered repo
+  prompt     Print the current repo and worktree for a shell prompt
+  setup      Re-run the post-checkout hook in the current worktree
+  forget     Unregister a repo from the gwt config
+  restore    Bring back a worktree removed with 'gwt rm --trash'
+  trash      List trashed worktrees ('gwt trash empty' purges them)
   shell-init Print shell integration for auto-cd
 
 Enhanced commands:
   add        Create a worktree (setup handled by post-checkout hook)
-  list/ls    List worktrees, marking the active one with '*' (green on a TTY)
+  list/ls    List worktrees, marking the active one with '*' (green on a TTY);
+             -s adds sizes, --sort=recent when each was last used
   remove/rm  Remove a worktree by path or branch name (auto-cd back)
-  use        Switch to an existing worktree by branch name`,
+  use        Switch to a worktree by (partial) branch name, or pick one`,
 }
 
 type hookOptions struct {
@@ -78,6 +95,7 @@ type hookOptions struct {
 	copyFiles      []string
 	versionManager string
 	packageManager string
+	sparse         []string
 	force          bool
 }
 
@@ -88,6 +106,149 @@ func repoBasePath(repo *git.Repo, mainBranch string) string {
 	return repo.Dir
 }
 
+// hookDataFor builds the template data for the repo's post-checkout hook.
+func hookDataFor(repo *git.Repo, opts hookOptions) hook.HookData {
+	return hook.HookData{
+		BasePath:       repoBasePath(repo, opts.mainBranch),
+		CopyFiles:      opts.copyFiles,
+		VersionManager: opts.versionManager,
+		PackageManager: opts.packageManager,
+		Version:        resolveVersion(),
+	}
+}
+
+// hookOptionsFromEntry rebuilds the hook options a registered repo was
+// initialized with, for regenerating or checking its hook. It never touches
+// the network: warnHookDrift calls it on every add, rm, use and ls.
+func hookOptionsFromEntry(repo *git.Repo, e config.RepoEntry) hookOptions {
+	return hookOptions{
+		mainBranch:     git.ResolveLocalMainBranch(repo.Dir, e.MainBranch),
+		sparse:         e.Sparse,
+		copyFiles:      e.CopyFiles,
+		versionManager: e.VersionManager,
+		packageManager: e.PackageManager,
+	}
+}
+
+// driftMessage describes how an installed hook differs from what gwt would
+// generate from data, or returns "" when there is nothing to report.
+func driftMessage(state hook.State, p hook.Provenance, data hook.HookData) string {
+	switch state {
+	case hook.StateMissing:
+		return "post-checkout hook is missing; run 'gwt init --upgrade' to regenerate it"
+	case hook.StateForeign:
+		return "post-checkout hook was not generated by this gwt (no provenance header); run 'gwt init --upgrade' to replace it"
+	case hook.StateEdited:
+		return fmt.Sprintf("post-checkout hook was edited by hand after gwt %s generated it; 'gwt init --upgrade' regenerates it, discarding the edits", p.Version)
+	case hook.StateStale:
+		if p.InputsHash == hook.InputsHash(data) {
+			return fmt.Sprintf("post-checkout hook was generated by gwt %s from an older template; run 'gwt init --upgrade' to regenerate it", p.Version)
+		}
+		return "post-checkout hook does not match this repo's registered config; run 'gwt init --upgrade' to regenerate it"
+	default:
+		return ""
+	}
+}
+
+// warnHookDrift warns on stderr when the repo's post-checkout hook has been
+// hand-edited or no longer matches what gwt would generate from the repo's
+// registered entry. Best-effort: lookup failures are ignored, and repos
+// registered without a hook are skipped.
+func warnHookDrift(repo *git.Repo) {
+	name, err := repo.CanonicalName()
+	if err != nil {
+		return
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return
+	}
+	entry, ok := cfg.Lookup(name)
+	if !ok {
+		return
+	}
+	opts := hookOptionsFromEntry(repo, entry)
+	if !hookHasWork(opts) {
+		return
+	}
+	hooksDir, err := repo.HooksDir()
+	if err != nil {
+		return
+	}
+	data := hook.Planned(hooksDir, hookDataFor(repo, opts))
+	state, p, err := hook.Check(hooksDir, data)
+	if err != nil {
+		return
+	}
+	if msg := driftMessage(state, p, data); msg != "" {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", msg)
+	}
+}
+
+// upgradeHook regenerates the repo's post-checkout hook from its registered
+// entry, overwriting whatever is installed.
+func upgradeHook(repo *git.Repo) error {
+	name, err := repo.CanonicalName()
+	if err != nil {
+		return fmt.Errorf("failed to determine repo name: %w", err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	entry, ok := cfg.Lookup(name)
+	if !ok {
+		return fmt.Errorf("%s is not registered; run 'gwt init' with hook flags first", name)
+	}
+	opts := hookOptionsFromEntry(repo, entry)
+	if !hookHasWork(opts) {
+		return fmt.Errorf("%s is registered without a hook (no copy files or managers); run 'gwt init' with hook flags instead", name)
+	}
+	opts.force = true
+
+	hooksDir, err := repo.HooksDir()
+	if err != nil {
+		return err
+	}
+	if state, _, err := hook.Check(hooksDir, hookDataFor(repo, opts)); err == nil && state == hook.StateCurrent {
+		fmt.Println("post-checkout hook is already up to date")
+		return nil
+	}
+	return setupHook(repo, opts)
+}
+
+// uninstallHook removes the repo's gwt-generated post-checkout hook,
+// restoring the hook it replaced, if any, and gwt's fetch refspec. The
+// refspec is removed even when there is no gwt hook, since a clone without
+// --with-hook sets it too.
+func uninstallHook(repo *git.Repo) error {
+	hooksDir, err := repo.HooksDir()
+	if err != nil {
+		return err
+	}
+	restored, hookErr := hook.Uninstall(hooksDir)
+	switch {
+	case errors.Is(hookErr, hook.ErrNotInstalled):
+		fmt.Printf("note: %v\n", hookErr)
+		hookErr = nil
+	case hookErr == nil:
+		fmt.Printf("post-checkout hook removed: %s/post-checkout\n", hooksDir)
+		if restored {
+			fmt.Println("restored the post-checkout hook gwt had replaced")
+		}
+	}
+	if repo.IsBare {
+		remotes, err := repo.UnconfigureFetch()
+		if err != nil {
+			return fmt.Errorf("failed to remove fetch refspec: %w", err)
+		}
+		for _, remote := range remotes {
+			fmt.Printf("removed the fetch refspec of %s; git fetch no longer updates its remote-tracking branches\n", remote)
+		}
+	}
+	return hookErr
+}
+
 func setupHook(repo *git.Repo, opts hookOptions) error {
 	if repo.IsBare {
 		if err := repo.ConfigureFetch(); err != nil {
@@ -95,21 +256,12 @@ func setupHook(repo *git.Repo, opts hookOptions) error {
 		}
 	}
 
-	basePath := repoBasePath(repo, opts.mainBranch)
-
 	hooksDir, err := repo.HooksDir()
 	if err != nil {
 		return err
 	}
 
-	data := hook.HookData{
-		BasePath:       basePath,
-		CopyFiles:      opts.copyFiles,
-		VersionManager: opts.versionManager,
-		PackageManager: opts.packageManager,
-	}
-
-	if err := hook.Install(hooksDir, data, opts.force); err != nil {
+	if err := hook.Install(hooksDir, hookDataFor(repo, opts), opts.force); err != nil {
 		return err
 	}
 
@@ -185,12 +337,42 @@ func worktreeBaseDir(repo *git.Repo) (baseDir, canonicalName string, err error)
 	return filepath.Join(dataDir, "worktrees", name), name, nil
 }
 
+// prepareWorktreeDir returns the directory new worktrees of repo go under,
+// creating it and registering a non-bare repo on first use, along with the
+// repo's config entry (zero when it is not registered).
+func prepareWorktreeDir(repo *git.Repo) (baseDir string, entry config.RepoEntry, err error) {
+	baseDir, canonicalName, err := worktreeBaseDir(repo)
+	if err != nil {
+		return "", config.RepoEntry{}, err
+	}
+
+	if !repo.IsBare {
+		if err := os.MkdirAll(baseDir, 0o755); err != nil {
+			return "", config.RepoEntry{}, fmt.Errorf("failed to create worktree directory: %w", err)
+		}
+		if err := ensureRegistered(repo, canonicalName); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to register repo in config: %v\n", err)
+		}
+	}
+
+	if cfg, cfgErr := config.Load(); cfgErr == nil {
+		entry, _ = cfg.Lookup(canonicalName)
+	}
+	return baseDir, entry, nil
+}
+
 var cloneCmd = &cobra.Command{
 	Use:   "clone <repository> [<directory>]",
 	Short: "Clone a repo into a bare-repo worktree structure",
 	Long: `Clones a repository as a bare repo inside a .bare/ directory,
 creates a .git file pointing to it, configures fetch, and fetches all branches.
 
+--filter, --depth and --single-branch narrow what is downloaded, and
+--reference reuses objects from a local repository. For a fork, --upstream
+adds the original repository as a second remote; the repo is then registered
+under the upstream's name. --sparse
+saves a sparse-checkout profile applied to every worktree 'gwt add' creates.
+
 If init flags (--main, --copy, --version-manager, --package-manager, --with-hook)
 are provided, a post-checkout hook is also created. Otherwise, run 'gwt init'
 afterward to generate the hook.`,
@@ -202,15 +384,44 @@ afterward to generate the hook.`,
 			dir = args[1]
 		}
 
-		absDir, err := git.Clone(url, dir)
+		filter, _ := cmd.Flags().GetString("filter")
+		depth, _ := cmd.Flags().GetInt("depth")
+		singleBranch, _ := cmd.Flags().GetBool("single-branch")
+		if depth < 0 {
+			return fmt.Errorf("invalid depth %d: must be positive", depth)
+		}
+		reference, _ := cmd.Flags().GetString("reference")
+		dissociate, _ := cmd.Flags().GetBool("dissociate")
+		upstream, _ := cmd.Flags().GetString("upstream")
+		if dissociate && reference == "" {
+			return fmt.Errorf("--dissociate requires --reference")
+		}
+		if reference != "" {
+			store, err := resolveReference(reference)
+			if err != nil {
+				return err
+			}
+			reference = store
+		}
+
+		absDir, err := git.Clone(url, dir, git.CloneOptions{
+			Filter:       filter,
+			Depth:        depth,
+			SingleBranch: singleBranch,
+			Reference:    reference,
+			Dissociate:   dissociate,
+			Upstream:     upstream,
+		})
 		if err != nil {
 			return err
 		}
 
 		mainBranch, _ := cmd.Flags().GetString("main")
+		mainBranch = git.ResolveMainBranch(absDir, mainBranch)
 		copyFiles, _ := cmd.Flags().GetStringSlice("copy")
 		versionManager, _ := cmd.Flags().GetString("version-manager")
 		packageManager, _ := cmd.Flags().GetString("package-manager")
+		sparse, _ := cmd.Flags().GetStringSlice("sparse")
 
 		if versionManager != "" && !validVersionManagers[versionManager] {
 			return fmt.Errorf("invalid version manager %q: must be one of: asdf, mise", versionManager)
@@ -225,6 +436,7 @@ afterward to generate the hook.`,
 			copyFiles:      copyFiles,
 			versionManager: versionManager,
 			packageManager: packageManager,
+			sparse:         sparse,
 		}
 
 		initFlags := []string{"main", "copy", "version-manager", "package-manager", "with-hook"}
@@ -262,7 +474,7 @@ afterward to generate the hook.`,
 			}
 		}
 
-		git.WriteCdFile(absDir)
+		git.WriteCd(absDir, absDir)
 
 		fmt.Printf("Cloned into %s\n", absDir)
 		fmt.Println("Next steps:")
@@ -275,6 +487,23 @@ afterward to generate the hook.`,
 	},
 }
 
+// resolveReference maps a --reference argument to the object store to borrow
+// from: a path to a local repository, or else a registered repo name.
+func resolveReference(ref string) (string, error) {
+	if fi, err := os.Stat(ref); err == nil && fi.IsDir() {
+		return git.ObjectStore(ref)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+	_, entry, err := cfg.ResolveRepo(ref)
+	if err != nil {
+		return "", fmt.Errorf("reference %q: no such directory, and %w", ref, err)
+	}
+	return git.ObjectStore(entry.Path)
+}
+
 var addCmd = &cobra.Command{
 	Use:   "add [flags] <branch>",
 	Short: "Create a worktree",
@@ -290,6 +519,28 @@ Use -b to create a new branch:
 Check out an existing branch:
   gwt add fix/login-bug
 
+A branch or start point that has not been fetched yet is fetched on its own
+(not the whole remote) and the new local branch tracks it. Pass --offline to
+never fetch.
+
+Check out a pull request (GitHub) or merge request (GitLab) by number:
+  gwt add --pr 123
+
+The pull request head is fetched from upstream (or origin) into the local
+branch pr/123, which 'git pull' keeps up to date; gwt ls shows the number.
+
+Move uncommitted changes from the current worktree into the new one:
+  gwt add -b feat/x --carry             # staged and unstaged changes
+  gwt add -b feat/x --carry-untracked   # untracked files too
+
+The current worktree is left clean, and a new branch without a start point
+starts from its HEAD. Changes that conflict with the new worktree are left
+with conflict markers there and also kept in the stash.
+
+Run from a subdirectory of a worktree, gwt add cds into the same subdirectory
+of the new worktree when it exists (or its nearest existing parent); pass
+--root to land at the worktree root instead.
+
 File copying and project setup are handled by the post-checkout hook
 installed via 'gwt init'.`,
 	DisableFlagParsing: true,
@@ -300,52 +551,130 @@ installed via 'gwt init'.`,
 			}
 		}
 
+		args, offline := stripOffline(args)
+		args, pr, err := stripPR(args)
+		if err != nil {
+			return err
+		}
+		if pr > 0 && offline {
+			return fmt.Errorf("--pr cannot be combined with --offline")
+		}
+		args, carry, carryUntracked := stripCarry(args)
+		args, root := stripRoot(args)
+
 		repo, err := git.NewRepo()
 		if err != nil {
 			return err
 		}
+		warnHookDrift(repo)
 
 		// Workspace fan-out: if this repo is a workspace member, create
 		// worktrees for all members instead of the single-repo flow.
 		if canonical, nameErr := repo.CanonicalName(); nameErr == nil {
 			if cfg, cfgErr := config.Load(); cfgErr == nil {
 				if wsName, ws, ok := cfg.WorkspaceForRepo(canonical); ok {
-					cd, addErr := runWorkspaceAdd(cfg, wsName, ws, args)
+					if pr > 0 {
+						return fmt.Errorf("--pr is not supported in workspace %q", wsName)
+					}
+					if carry {
+						return fmt.Errorf("--carry is not supported in workspace %q", wsName)
+					}
+					cd, addErr := runWorkspaceAdd(cfg, wsName, ws, args, offline)
 					if addErr != nil {
 						return addErr
 					}
-					git.WriteCdFile(cd)
+					cdTo(cd)
 					return nil
 				}
 			}
 		}
 
-		baseDir, canonicalName, err := worktreeBaseDir(repo)
+		baseDir, entry, err := prepareWorktreeDir(repo)
 		if err != nil {
 			return err
 		}
 
-		if !repo.IsBare {
-			if err := os.MkdirAll(baseDir, 0o755); err != nil {
-				return fmt.Errorf("failed to create worktree directory: %w", err)
+		if pr > 0 {
+			branch := git.PRBranch(pr)
+			if a, parseErr := git.ParseAddArgs(append(args, branch)); parseErr != nil || a.BranchFlag != "" || a.Branch != branch {
+				return fmt.Errorf("--pr %d checks out branch %s; drop the branch argument", pr, branch)
 			}
-			if err := ensureRegistered(repo, canonicalName); err != nil {
-				fmt.Fprintf(os.Stderr, "warning: failed to register repo in config: %v\n", err)
+			if path, ok, _ := repo.FindWorktreeByBranch(branch); ok {
+				fmt.Fprintf(os.Stderr, "%s is already checked out at %s; run 'git pull' there to update it\n", branch, path)
+				cdIntoWorktree(path, root)
+				return nil
 			}
+			if _, err := git.FetchPullRequest(repo.Dir, pr); err != nil {
+				return err
+			}
+			args = append(args, branch)
 		}
 
-		path, err := repo.Add(args, baseDir)
+		opts := git.AddOptions{Sparse: entry.Sparse, Offline: offline}
+		var path string
+		if carry {
+			path, err = addWithCarry(repo, args, baseDir, opts, carryUntracked)
+		} else {
+			path, err = repo.Add(args, baseDir, opts)
+		}
 		if err == nil && path != "" {
-			git.WriteCdFile(path)
+			cdIntoWorktree(path, root)
 		}
 		return err
 	},
 }
 
+// addWithCarry creates a worktree like Repo.Add and moves the current
+// worktree's uncommitted changes into it, leaving the current worktree clean.
+// A new branch without an explicit start point starts from the current
+// worktree's HEAD, so the changes apply on the commit they were made on.
+func addWithCarry(repo *git.Repo, args []string, baseDir string, opts git.AddOptions, untracked bool) (string, error) {
+	source, head, err := git.CarrySource()
+	if err != nil {
+		return "", fmt.Errorf("--carry must be run inside the worktree whose changes to carry: %w", err)
+	}
+	a, err := git.ParseAddArgs(args)
+	if err != nil {
+		return "", err
+	}
+	if a.BranchFlag != "" && len(a.Extra) == 0 {
+		args = append(args, head)
+	}
+
+	c, ok, err := git.StartCarry(source, untracked, "gwt add --carry "+a.Branch)
+	if err != nil {
+		return "", err
+	}
+	path, err := repo.Add(args, baseDir, opts)
+	if err != nil {
+		if ok {
+			if abortErr := c.Abort(); abortErr != nil {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", abortErr)
+			}
+		}
+		return "", err
+	}
+	if !ok {
+		fmt.Fprintln(os.Stderr, "nothing to carry: the current worktree has no uncommitted changes")
+		return path, nil
+	}
+	conflicts, err := c.Apply(path)
+	if err != nil {
+		return path, err
+	}
+	if len(conflicts) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: carried changes conflict in %d file(s): %s\n", len(conflicts), strings.Join(conflicts, ", "))
+		fmt.Fprintf(os.Stderr, "         resolve them in %s; the changes are also kept in %s\n", path, c.Ref())
+		return path, nil
+	}
+	fmt.Printf("carried uncommitted changes from %s\n", source)
+	return path, nil
+}
+
 // partitionRemoveArgs separates flags from positional arguments for the remove
 // command. It sets force true when a flag is -f, --force, or starts with
 // --force=. A -- separator causes all subsequent args to be treated as
-// positionals, mirroring the style used by buildAddArgs and Remove.
+// positionals, mirroring the style used by Remove.
 func partitionRemoveArgs(args []string) (force bool, positionals []string) {
 	pastSeparator := false
 	for _, a := range args {
@@ -368,6 +697,110 @@ func partitionRemoveArgs(args []string) (force bool, positionals []string) {
 	return force, positionals
 }
 
+// stripOffline removes gwt's own --offline flag from `gwt add` arguments
+// before they are passed to git.
+func stripOffline(args []string) (cleaned []string, offline bool) {
+	for _, a := range args {
+		if a == "--offline" {
+			offline = true
+		} else {
+			cleaned = append(cleaned, a)
+		}
+	}
+	return cleaned, offline
+}
+
+// stripRoot removes gwt's own --root flag from `gwt add` arguments: cd to
+// the new worktree's root instead of the current subdirectory's counterpart.
+func stripRoot(args []string) (cleaned []string, root bool) {
+	for _, a := range args {
+		if a == "--root" {
+			root = true
+		} else {
+			cleaned = append(cleaned, a)
+		}
+	}
+	return cleaned, root
+}
+
+// stripPR removes gwt's own --pr <number> (or --pr=<number>) flag from
+// `gwt add` arguments and returns the pull request number, or 0 when absent.
+func stripPR(args []string) (cleaned []string, number int, err error) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		var value string
+		switch {
+		case a == "--pr":
+			if i+1 >= len(args) {
+				return nil, 0, fmt.Errorf("--pr requires a pull request number")
+			}
+			value = args[i+1]
+			i++
+		case strings.HasPrefix(a, "--pr="):
+			value = strings.TrimPrefix(a, "--pr=")
+		default:
+			cleaned = append(cleaned, a)
+			continue
+		}
+		number, err = strconv.Atoi(strings.TrimPrefix(value, "#"))
+		if err != nil || number <= 0 {
+			return nil, 0, fmt.Errorf("--pr: invalid pull request number %q", value)
+		}
+	}
+	return cleaned, number, nil
+}
+
+// stripTrash removes gwt's own --trash and --no-trash flags from `gwt rm`
+// arguments before they are passed to git.
+func stripTrash(args []string) (cleaned []string, trash, noTrash bool) {
+	cleaned = []string{}
+	for _, a := range args {
+		switch a {
+		case "--trash":
+			trash = true
+		case "--no-trash":
+			noTrash = true
+		default:
+			cleaned = append(cleaned, a)
+		}
+	}
+	return cleaned, trash, noTrash
+}
+
+// stripRemote removes gwt's own --remote and --force-remote flags from
+// `gwt rm` arguments before they are passed to git. --force-remote implies
+// --remote.
+func stripRemote(args []string) (cleaned []string, remote, forceRemote bool) {
+	cleaned = []string{}
+	for _, a := range args {
+		switch a {
+		case "--remote":
+			remote = true
+		case "--force-remote":
+			remote, forceRemote = true, true
+		default:
+			cleaned = append(cleaned, a)
+		}
+	}
+	return cleaned, remote, forceRemote
+}
+
+// stripCarry removes gwt's own --carry and --carry-untracked flags from
+// `gwt add` arguments. --carry-untracked implies --carry.
+func stripCarry(args []string) (cleaned []string, carry, untracked bool) {
+	for _, a := range args {
+		switch a {
+		case "--carry":
+			carry = true
+		case "--carry-untracked":
+			carry, untracked = true, true
+		default:
+			cleaned = append(cleaned, a)
+		}
+	}
+	return cleaned, carry, untracked
+}
+
 func stripKeepBranch(args []string) (cleaned []string, keepBranch bool) {
 	for _, a := range args {
 		if a == "--keep-branch" || a == "-k" {
@@ -391,15 +824,48 @@ var removeCmd = &cobra.Command{
 Accepts a branch name or a path. If the argument matches a worktree branch,
 it resolves to that worktree's path.
 
+Remove several worktrees at once by branch pattern, by whether they are
+merged into the main branch, or by how long they have been idle (combine
+them to narrow the selection). The matches are listed and confirmed once:
+  gwt rm 'feat/*'
+  gwt rm --merged
+  gwt rm --older-than 30d
+
 In a workspace, the whole branch group (all member worktrees) is removed;
 the argument may be a branch name, a member worktree path, or the group
 directory.
 
+Before removing anything, gwt checks for uncommitted changes, untracked
+(non-ignored) files, stashes made on the branch, and commits that are on
+no remote, and refuses with a summary unless --force is given.
+
+With --trash, the worktree directory (untracked and ignored files
+included) is moved to gwt's trash instead of deleted, and its branch is
+kept; bring it back with 'gwt restore'. Set 'trash = true' at the top of the
+gwt config to make this the default, and pass --no-trash to delete anyway.
+Workspace groups cannot be trashed: rm refuses them while trash is the
+default, so pass --no-trash to delete one.
+
 After removal, the shell wrapper (from 'gwt shell-init') will cd back
 to the repository root.
 
+With --remote, the branch's remote branch (its upstream, or origin/<branch>)
+is deleted too, but only while it is at the same commit as the local branch;
+--force-remote deletes it regardless. The remote's default branch is never
+deleted, and --force alone never deletes anything on the remote. --remote
+deletes the worktree, so when trash is the configured default it needs
+--no-trash as well.
+
 Flags:
   -k, --keep-branch   Keep the branch after removing the worktree
+      --remote        Also delete the remote branch
+      --force-remote  Delete the remote branch even if it diverged (implies --remote)
+      --merged        Select worktrees whose HEAD is merged into the main branch
+                      (not ones still at the main branch's commit)
+      --older-than    Select worktrees idle for at least this long (e.g. 30d)
+  -y, --yes           Remove selected worktrees without asking
+      --trash         Move the worktree to the trash instead of deleting it
+      --no-trash      Delete even when trash is the configured default
 
 Supports all git worktree remove flags (e.g., --force).`,
 	DisableFlagParsing: true,
@@ -412,18 +878,49 @@ Supports all git worktree remove flags (e.g., --force).`,
 		}
 
 		args, keepBranch := stripKeepBranch(args)
+		args, trash, noTrash := stripTrash(args)
+		if trash && noTrash {
+			return fmt.Errorf("--trash and --no-trash are mutually exclusive")
+		}
+		args, deleteRemote, forceRemote := stripRemote(args)
+		if deleteRemote && trash {
+			return fmt.Errorf("--remote cannot be combined with --trash")
+		}
+		sel, yes, args, err := parseBulkRemoveArgs(args)
+		if err != nil {
+			return err
+		}
 
 		repo, err := git.NewRepo()
 		if err != nil {
 			return err
 		}
+		warnHookDrift(repo)
+
+		cfg, cfgErr := config.Load()
+		trashByDefault := false
+		if !trash && !noTrash && cfgErr == nil && cfg.Trash {
+			if deleteRemote {
+				return fmt.Errorf("--remote deletes the worktree, but the gwt config makes the trash the default; pass --no-trash as well")
+			}
+			trash, trashByDefault = true, true
+		}
 
 		// Workspace teardown: if this repo is a workspace member, remove the
 		// whole branch group, identified by the argument (branch name or
 		// path) or, absent one, by the current worktree.
 		if canonical, nameErr := repo.CanonicalName(); nameErr == nil {
-			if cfg, cfgErr := config.Load(); cfgErr == nil {
+			if cfgErr == nil {
 				if wsName, ws, ok := cfg.WorkspaceForRepo(canonical); ok {
+					if trashByDefault {
+						return fmt.Errorf("workspace groups cannot be moved to the trash, which the gwt config makes the default; pass --no-trash to delete the group")
+					}
+					if trash {
+						return fmt.Errorf("--trash is not supported for workspace groups")
+					}
+					if sel.active() {
+						return fmt.Errorf("bulk removal is not supported in workspace %q; remove groups one at a time", wsName)
+					}
 					force, positionals := partitionRemoveArgs(args)
 					if len(positionals) > 1 {
 						return fmt.Errorf("expected at most one worktree, got %d: %v", len(positionals), positionals)
@@ -452,9 +949,10 @@ Supports all git worktree remove flags (e.g., --force).`,
 					if err != nil {
 						return err
 					}
-					cd, rmErr := runWorkspaceRemove(cfg, wsName, ws, group, keepBranch, force)
+					opts := git.RemoveOptions{KeepBranch: keepBranch, Remote: deleteRemote, ForceRemote: forceRemote, Force: force}
+					cd, rmErr := runWorkspaceRemove(cfg, wsName, ws, group, opts)
 					if cd != "" {
-						git.WriteCdFile(cd)
+						leaveTo(cd)
 					}
 					if rmErr != nil {
 						return rmErr
@@ -464,6 +962,13 @@ Supports all git worktree remove flags (e.g., --force).`,
 			}
 		}
 
+		if sel.active() {
+			force, _ := partitionRemoveArgs(args)
+			opts := git.RemoveOptions{KeepBranch: keepBranch, Remote: deleteRemote, ForceRemote: forceRemote, Force: force}
+			p := prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+			return runBulkRemove(p, repo, sel, yes, opts, trash)
+		}
+
 		// Resolve branch names to worktree paths.
 		resolvedArgs := make([]string, len(args))
 		copy(resolvedArgs, args)
@@ -476,7 +981,11 @@ Supports all git worktree remove flags (e.g., --force).`,
 			}
 		}
 
-		res, err := repo.Remove(resolvedArgs, keepBranch)
+		if trash {
+			return trashWorktree(repo, resolvedArgs)
+		}
+
+		res, err := repo.Remove(resolvedArgs, git.RemoveOptions{KeepBranch: keepBranch, Remote: deleteRemote, ForceRemote: forceRemote})
 		if err != nil {
 			return err
 		}
@@ -499,12 +1008,42 @@ Supports all git worktree remove flags (e.g., --force).`,
 		} else {
 			fmt.Printf("removed worktree %s\n", name)
 		}
+		if res.Remote.Deleted != "" {
+			fmt.Printf("deleted remote branch %s\n", res.Remote.Deleted)
+		}
+		if res.Remote.Kept != "" {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", res.Remote.Kept)
+		}
 
-		git.WriteCdFile(res.RepoDir)
+		forgetVisits(res.WorktreePath)
+		leaveTo(res.RepoDir)
 		return nil
 	},
 }
 
+// trashWorktree moves the worktree named by args to the trash, cleans up the
+// empty parent dirs it leaves behind, and cds back to the repo.
+func trashWorktree(repo *git.Repo, args []string) error {
+	trashDir, err := config.TrashDir()
+	if err != nil {
+		return err
+	}
+	e, err := repo.Trash(args, trashDir)
+	if err != nil {
+		return err
+	}
+	if dataDir, dataErr := config.DataDir(); dataErr == nil {
+		worktreeRoot := filepath.Join(dataDir, "worktrees")
+		if strings.HasPrefix(e.Path, worktreeRoot+string(filepath.Separator)) {
+			git.CleanEmptyParents(filepath.Dir(e.Path), worktreeRoot)
+		}
+	}
+	fmt.Printf("moved worktree %s to the trash — restore with 'gwt restore %s'\n", e.Name(), e.Name())
+	forgetVisits(e.Path)
+	leaveTo(repo.Dir)
+	return nil
+}
+
 // completeWorktreeBranches provides tab-completion of worktree branch names.
 func completeWorktreeBranches(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	if len(args) > 0 {
@@ -528,66 +1067,302 @@ func completeWorktreeBranches(cmd *cobra.Command, args []string, toComplete stri
 }
 
 var useCmd = &cobra.Command{
-	Use:   "use <branch>",
-	Short: "Switch to an existing worktree by branch name",
-	Long: `Navigate to an existing worktree that has the given branch checked out.
-
-If no worktree is found for the branch, suggests creating one with 'gwt add'.
+	Use:   "use [-c] [<branch> | <repo>:<branch> | -]",
+	Short: "Switch to a worktree by abbreviated branch or directory name",
+	Long: `Navigate to an existing worktree by branch or directory name.
+
+The name may be abbreviated: an exact match wins, then a prefix, then a
+substring, then the letters in order (e.g. 'fl' for fix/login). When several
+worktrees match equally well, they are listed and nothing happens. If none
+matches, suggests creating one with 'gwt add'. When the name was abbreviated,
+the worktree it matched is named on stderr.
+
+'gwt use -' returns to the worktree gwt last switched to before the current
+one, like 'cd -', across repos too. Every worktree gwt add, use and jump
+send you to is recorded for it.
+
+Prefix the branch with a registered repo to switch to a worktree of another
+repo from anywhere, e.g. 'gwt use owner/repo:feat/x' or 'gwt use repo:feat'.
+The repo is its canonical name or unique last segment; 'gwt use repo:' goes to
+its main branch.
+
+Without a name, on a terminal, an interactive picker lists every worktree with
+its path and whether it has uncommitted changes: type to filter, arrow keys
+or Ctrl-P/Ctrl-N to move, Enter to switch, Esc to cancel.
+
+With -c/--create, a branch without a worktree gets one: the local branch,
+or origin's (else upstream's) branch of that name, is checked out, or else a
+new branch is created from the main branch. In a workspace, the whole branch group is
+created as by 'gwt add'. The branch must then be given in full.
+
+From a subdirectory of the current worktree, gwt use lands in the same
+subdirectory of the target worktree (or its nearest existing parent) when the
+target is in the same repo; --root goes to the worktree root instead.
 
 Requires shell integration (eval "$(gwt shell-init)") for the cd to work.`,
-	Args:              cobra.ExactArgs(1),
+	Args:              cobra.MaximumNArgs(1),
 	ValidArgsFunction: completeWorktreeBranches,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		branch := args[0]
+		var chosen useCandidate
+		abbreviated := false // chosen matched the argument only in part
+		if create, _ := cmd.Flags().GetBool("create"); create {
+			if len(args) == 0 || args[0] == "-" || strings.Contains(args[0], ":") {
+				return fmt.Errorf("--create needs a branch of the current repo")
+			}
+			repo, err := git.NewRepo()
+			if err != nil {
+				return err
+			}
+			warnHookDrift(repo)
+			if chosen.path, err = useOrCreate(repo, args[0]); err != nil {
+				return err
+			}
+		} else if len(args) == 1 && args[0] == "-" {
+			current := git.CurrentWorktreeTop()
+			path, err := previousWorktree(current)
+			if err != nil {
+				return err
+			}
+			chosen = useCandidate{path: path}
+		} else if len(args) == 1 && strings.Contains(args[0], ":") {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			m, err := resolveRepoUse(cfg, args[0])
+			if err != nil {
+				return err
+			}
+			chosen, abbreviated = m.useCandidate, m.tier != matchExact
+		} else {
+			repo, err := git.NewRepo()
+			if err != nil {
+				return err
+			}
+			warnHookDrift(repo)
 
-		repo, err := git.NewRepo()
+			cands, err := useCandidates(repo)
+			if err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				var m useMatch
+				m, err = resolveUse(cands, args[0])
+				chosen, abbreviated = m.useCandidate, m.tier != matchExact
+			} else {
+				if !stdinIsTerminal() {
+					return fmt.Errorf("a branch is required when not running on a terminal")
+				}
+				fillDirty(cands)
+				chosen, err = runPicker(cands, nil)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if abbreviated {
+			fmt.Fprintf(os.Stderr, "%s matched %s\n", args[0], chosen.label())
+		}
+		root, _ := cmd.Flags().GetBool("root")
+		fmt.Println(cdIntoWorktree(chosen.path, root))
+		return nil
+	},
+}
+
+var jumpCmd = &cobra.Command{
+	Use:   "jump [<query>]",
+	Short: "Switch to the best matching worktree of any registered repo",
+	Long: `Switch to a worktree of any registered repo, from anywhere on disk.
+
+The query is matched like 'gwt use' against every worktree's branch, directory
+name and repo:branch label. The best kind of match wins: exact, then prefix,
+substring and fuzzy. Among equally good matches, the worktree visited most
+often and most recently (frecency) with 'gwt add', 'gwt use' or 'gwt jump'
+wins.
+
+Without a query, on a terminal, an interactive picker lists every worktree,
+most frecent first, and ranks what you type the same way.
+
+Requires shell integration (eval "$(gwt shell-init)") for the cd to work.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
-
-		path, found, err := repo.FindWorktreeByBranch(branch)
+		h, err := config.LoadHistory()
 		if err != nil {
 			return err
 		}
-		if !found {
-			return fmt.Errorf("no worktree found for branch %q\nRun 'gwt add %s' to create one", branch, branch)
+		cands := jumpCandidates(cfg)
+		if len(cands) == 0 {
+			return fmt.Errorf("no registered repos have worktrees; register one with 'gwt init'")
+		}
+		query := ""
+		if len(args) == 1 {
+			query = args[0]
+		}
+		scores := h.Frecency(time.Now())
+		ranked := rankJump(cands, scores, query)
+
+		var chosen useCandidate
+		if query != "" {
+			if len(ranked) == 0 {
+				return fmt.Errorf("no worktree in any registered repo matches %q", query)
+			}
+			chosen = ranked[0].useCandidate
+		} else {
+			if !stdinIsTerminal() {
+				return fmt.Errorf("a query is required when not running on a terminal")
+			}
+			ordered := make([]useCandidate, len(ranked))
+			for i, m := range ranked {
+				ordered[i] = m.useCandidate
+			}
+			fillDirty(ordered)
+			if chosen, err = runPicker(ordered, scores); err != nil {
+				return err
+			}
 		}
 
-		git.WriteCdFile(path)
-		fmt.Println(path)
+		cdTo(chosen.path)
+		fmt.Println(chosen.path)
 		return nil
 	},
 }
 
-var shellInitCmd = &cobra.Command{
-	Use:   "shell-init",
-	Short: "Print shell integration code for auto-cd",
-	Long: `Outputs a shell wrapper function that automatically cd's into newly
-created worktrees or cloned repos.
+var promptCmd = &cobra.Command{
+	Use:   "prompt [--format <template>]",
+	Short: "Print the current repo and worktree for a shell prompt",
+	Long: `Prints the repo, branch and workspace of the current directory for use in
+PS1 or a starship custom module, and nothing outside a repository. It reads
+the files under .git directly rather than running git, so it stays fast.
+
+The output is a Go template over these fields:
+  .Repo         registered short name, or the repo directory's name
+  .Branch       branch, or the abbreviated commit when detached
+  .Worktree     worktree directory name
+  .Workspace    workspace the repo belongs to, if any
+  .Detached     HEAD is detached
+  .Dirty        uncommitted changes (refreshed in the background, so it
+                can lag a couple of seconds behind)
+  .SetupFailed  the post-checkout hook failed when gwt created the worktree
+                (gwt setup re-runs it and clears this)
+
+The template comes from --format, else prompt_format in the config, else:
+  ` + defaultPromptFormat + `
+
+Examples:
+  PS1='$(gwt prompt) \$ '
+  gwt prompt --format '{{.Branch}}{{if .Dirty}} ✗{{end}}'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if worktree, _ := cmd.Flags().GetString("refresh"); worktree != "" {
+			return refreshDirty(worktree)
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil
+		}
+		state, ok := git.ReadWorktreeState(cwd)
+		if !ok {
+			return nil
+		}
+		cfg, _ := config.Load()
+		format, _ := cmd.Flags().GetString("format")
+		if format == "" && cfg != nil {
+			format = cfg.PromptFormat
+		}
+		if format == "" {
+			format = defaultPromptFormat
+		}
+		d := promptDataFor(cfg, state)
+		if state.Worktree != "" && strings.Contains(format, ".Dirty") {
+			d.Dirty = cachedDirty(state.Worktree, refreshDirtyInBackground)
+		}
+		return renderPrompt(os.Stdout, format, d)
+	},
+}
 
-Add to your shell profile:
-  eval "$(gwt shell-init)"`,
+var setupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Re-run the post-checkout hook in the current worktree",
+	Long: `Runs the repo's post-checkout hook in the current worktree as if the
+worktree had just been created: files are copied and dependencies installed
+again. Use it after fixing whatever made setup fail; on success it clears the
+setup-failed marker gwt prompt shows.`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		fmt.Print(shellWrapper)
-		return nil
+		top := git.CurrentWorktreeTop()
+		if top == "" {
+			return fmt.Errorf("not inside a git worktree")
+		}
+		cmd.SilenceUsage = true
+		return git.RunPostCheckout(top)
 	},
 }
 
+var shellInitCmd = &cobra.Command{
+	Use:   "shell-init [bash|zsh|fish|nu|pwsh]",
+	Short: "Print shell integration code for auto-cd",
+	Long: `Outputs a shell wrapper function that lets gwt change the calling shell:
+cd into newly created worktrees or cloned repos, and apply a worktree's
+.gwt-env (exports, unsets and scripts to source) on the way in. It also
+enables tab-completion. Without an argument the wrapper for bash and zsh is
+printed.
+
+Add to your shell profile:
+  bash/zsh (~/.bashrc, ~/.zshrc):
+    eval "$(command gwt shell-init)"
+  fish (~/.config/fish/config.fish):
+    command gwt shell-init fish | source
+  nushell (env.nu, then config.nu):
+    ^gwt shell-init nu | save --force ~/.cache/gwt.nu
+    source ~/.cache/gwt.nu
+  PowerShell ($PROFILE):
+    Invoke-Expression (& gwt shell-init pwsh | Out-String)`,
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"bash", "zsh", "sh", "fish", "nu", "pwsh", "powershell"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell := "bash"
+		if len(args) == 1 {
+			shell = args[0]
+		}
+		fmt.Print(shellWrappers[shell])
+		return nil
+	},
+}
+
+// shellWrappers maps each shell-init argument to its wrapper.
+var shellWrappers = map[string]string{
+	"bash":       shellWrapper,
+	"zsh":        shellWrapper,
+	"sh":         shellWrapper,
+	"fish":       fishWrapper,
+	"nu":         nuWrapper,
+	"pwsh":       pwshWrapper,
+	"powershell": pwshWrapper,
+}
+
 const shellWrapper = `gwt() {
-    if [ "${1}" = "add" ] || [ "${1}" = "clone" ] || [ "${1}" = "rm" ] || [ "${1}" = "remove" ] || [ "${1}" = "use" ]; then
-        local _gwt_cd_file
-        _gwt_cd_file=$(mktemp)
-        GWT_CD_FILE="$_gwt_cd_file" command gwt "$@"
-        local _gwt_exit=$?
-        if [ -s "$_gwt_cd_file" ]; then
-            builtin cd "$(cat "$_gwt_cd_file")" || true
-        fi
-        rm -f "$_gwt_cd_file"
-        return $_gwt_exit
-    else
-        command gwt "$@"
-    fi
+    case "${1:-}" in
+        prompt|__complete*) command gwt "$@"; return ;;
+    esac
+    local _gwt_directives _gwt_exit _gwt_line
+    _gwt_directives=$(mktemp)
+    GWT_DIRECTIVE_FILE="$_gwt_directives" command gwt "$@"
+    _gwt_exit=$?
+    while IFS= read -r _gwt_line <&3; do
+        case "$_gwt_line" in
+            "cd "*) builtin cd "${_gwt_line#cd }" || true ;;
+            "export "*) export "${_gwt_line#export }" ;;
+            "unset "*) unset "${_gwt_line#unset }" ;;
+            "source "*) . "${_gwt_line#source }" ;;
+        esac
+    done 3< "$_gwt_directives"
+    rm -f "$_gwt_directives"
+    return $_gwt_exit
 }
 
 # Enable tab-completion for gwt
@@ -598,22 +1373,156 @@ elif [ -n "${BASH_VERSION:-}" ]; then
 fi
 `
 
+const fishWrapper = `function gwt --description 'gwt with auto-cd and worktree environments'
+    switch "$argv[1]"
+        case prompt '__complete*'
+            command gwt $argv
+            return
+    end
+    set -l _gwt_directives (mktemp)
+    GWT_DIRECTIVE_FILE=$_gwt_directives GWT_SHELL=fish command gwt $argv
+    set -l _gwt_exit $status
+    for _gwt_line in (cat $_gwt_directives)
+        set -l _gwt_arg (string split -m 1 ' ' -- $_gwt_line)[2]
+        switch $_gwt_line
+            case 'cd *'
+                builtin cd $_gwt_arg
+            case 'export *'
+                set -l _gwt_kv (string split -m 1 = -- $_gwt_arg)
+                set -gx $_gwt_kv[1] $_gwt_kv[2]
+            case 'unset *'
+                set -e -g $_gwt_arg
+            case 'source *'
+                source $_gwt_arg
+        end
+    end
+    rm -f $_gwt_directives
+    return $_gwt_exit
+end
+
+# Enable tab-completion for gwt
+command gwt completion fish | source
+`
+
+const nuWrapper = `# Tab-completion for gwt, answered by cobra's hidden __complete command
+def "nu-complete gwt" [context: string] {
+    let words = ($context | split row -r '\s+')
+    ^gwt __complete ...($words | skip 1)
+    | lines
+    | where {|line| not ($line | str starts-with ":") }
+    | each {|line|
+        let parts = ($line | split row "\t")
+        { value: $parts.0, description: (if ($parts | length) > 1 { $parts.1 } else { "" }) }
+    }
+}
+
+def --env --wrapped gwt [...args: string@"nu-complete gwt"] {
+    if ($args | length) > 0 and ($args.0 == "prompt" or ($args.0 | str starts-with "__complete")) {
+        ^gwt ...$args
+        return
+    }
+    let directives = (mktemp -t)
+    let exit_code = try {
+        with-env { GWT_DIRECTIVE_FILE: $directives, GWT_SHELL: nu } { ^gwt ...$args }
+        0
+    } catch {
+        $env.LAST_EXIT_CODE
+    }
+    for line in (open --raw $directives | into string | lines) {
+        let parts = ($line | split row --number 2 " ")
+        let arg = ($parts | get 1)
+        match ($parts | get 0) {
+            "cd" => { cd $arg }
+            "export" => {
+                let kv = ($arg | split row --number 2 "=")
+                load-env { ($kv | get 0): ($kv | get 1) }
+            }
+            "unset" => { hide-env --ignore-errors $arg }
+            "source" => { print --stderr $"gwt: nushell cannot source ($arg) at run time; source it yourself" }
+        }
+    }
+    rm -f $directives
+    if $exit_code != 0 {
+        error make --unspanned { msg: $"gwt exited with code ($exit_code)" }
+    }
+}
+`
+
+const pwshWrapper = `function gwt {
+    $gwtExe = Get-Command gwt -CommandType Application | Select-Object -First 1
+    if ($args.Count -gt 0 -and ($args[0] -eq 'prompt' -or $args[0] -like '__complete*')) {
+        & $gwtExe @args
+        return
+    }
+    $directives = [System.IO.Path]::GetTempFileName()
+    $env:GWT_DIRECTIVE_FILE = $directives
+    $env:GWT_SHELL = 'pwsh'
+    try {
+        & $gwtExe @args
+        $gwtExit = $LASTEXITCODE
+    } finally {
+        Remove-Item Env:GWT_DIRECTIVE_FILE, Env:GWT_SHELL -ErrorAction SilentlyContinue
+    }
+    foreach ($line in Get-Content -LiteralPath $directives) {
+        $op, $arg = $line -split ' ', 2
+        switch ($op) {
+            'cd' { Set-Location -LiteralPath $arg }
+            'export' {
+                $name, $value = $arg -split '=', 2
+                Set-Item -LiteralPath "Env:$name" -Value $value
+            }
+            'unset' { Remove-Item -LiteralPath "Env:$arg" -ErrorAction SilentlyContinue }
+            'source' { . $arg }
+        }
+    }
+    Remove-Item -LiteralPath $directives -Force -ErrorAction SilentlyContinue
+    $global:LASTEXITCODE = $gwtExit
+}
+
+# Enable tab-completion for gwt
+& (Get-Command gwt -CommandType Application | Select-Object -First 1) completion powershell | Out-String | Invoke-Expression
+`
+
 var validVersionManagers = map[string]bool{"asdf": true, "mise": true}
 var validPackageManagers = map[string]bool{"pnpm": true, "npm": true, "yarn": true}
 
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Generate a post-checkout hook for worktree setup",
+	Long: `Registers the repo in the gwt config and, when hook flags are given,
+generates a post-checkout hook that sets up new worktrees.
+
+Run with no flags in a terminal to be walked through it interactively: the
+main branch (pre-filled from origin/HEAD), the detected version and package
+managers, which gitignored files to copy into new worktrees, and a preview of
+the hook before anything is written.
+
+--uninstall is the inverse: it removes the generated hook (putting back any
+hook it replaced) and, in a bare repo, the fetch refspec gwt configured. The
+refspec is removed even when no gwt hook is installed, as after a clone
+without --with-hook. Use 'gwt forget' to unregister the repo as well.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		repo, err := git.NewRepo()
 		if err != nil {
 			return err
 		}
 
+		if upgrade, _ := cmd.Flags().GetBool("upgrade"); upgrade {
+			return upgradeHook(repo)
+		}
+		if uninstall, _ := cmd.Flags().GetBool("uninstall"); uninstall {
+			return uninstallHook(repo)
+		}
+		if cmd.Flags().NFlag() == 0 && stdinIsTerminal() {
+			return runInitWizard(prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout}, repo)
+		}
+
 		mainBranch, _ := cmd.Flags().GetString("main")
+		mainBranch = git.ResolveMainBranch(repo.Dir, mainBranch)
 		copyFiles, _ := cmd.Flags().GetStringSlice("copy")
 		versionManager, _ := cmd.Flags().GetString("version-manager")
 		packageManager, _ := cmd.Flags().GetString("package-manager")
+		sparse, _ := cmd.Flags().GetStringSlice("sparse")
 		force, _ := cmd.Flags().GetBool("force")
 
 		if versionManager != "" && !validVersionManagers[versionManager] {
@@ -628,12 +1537,17 @@ var initCmd = &cobra.Command{
 			copyFiles:      copyFiles,
 			versionManager: versionManager,
 			packageManager: packageManager,
+			sparse:         sparse,
 			force:          force,
 		}
 
 		wantHook := cmd.Flags().Changed("copy") || cmd.Flags().Changed("version-manager") ||
 			cmd.Flags().Changed("package-manager") || cmd.Flags().Changed("with-hook")
 
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			return printInitPlan(os.Stdout, repo, opts, wantHook, cmd.Flags().Changed("version-manager"), cmd.Flags().Changed("package-manager"))
+		}
+
 		detected := false
 		if wantHook {
 			opts, detected = detectAndMerge(repo, opts, cmd.Flags().Changed("version-manager"), cmd.Flags().Changed("package-manager"))
@@ -667,8 +1581,443 @@ var initCmd = &cobra.Command{
 	},
 }
 
+// describeSource names where detection looked, for user-facing output.
+func describeSource(src detect.FileSource) string {
+	switch s := src.(type) {
+	case detect.DirSource:
+		return s.Root
+	case detect.GitSource:
+		return s.Ref + " (git tree)"
+	default:
+		return "repo"
+	}
+}
+
+// describeManager renders one detection dimension for printInitPlan.
+func describeManager(value string, explicit bool, detected, signal string) string {
+	switch {
+	case explicit && detected != "" && detected != value:
+		return fmt.Sprintf("%s (from flag; detected %s in %s)", value, detected, signal)
+	case explicit:
+		return fmt.Sprintf("%s (from flag)", value)
+	case detected != "":
+		return fmt.Sprintf("%s (from %s)", detected, signal)
+	default:
+		return "none detected"
+	}
+}
+
+// printInitPlan reports what `gwt init` would do with opts, without touching
+// the repo or the config: what detection found and from which signal file,
+// the [repos] entry registerRepo would write, and the hook Generate would
+// produce with a unified diff against the installed one.
+func printInitPlan(w io.Writer, repo *git.Repo, opts hookOptions, wantHook, vmSet, pmSet bool) error {
+	src := fileSourceFor(repo, opts.mainBranch)
+	res := detect.Detect(src, exec.LookPath)
+	fmt.Fprintf(w, "Detection (in %s):\n", describeSource(src))
+	fmt.Fprintf(w, "  version manager: %s\n", describeManager(opts.versionManager, vmSet, res.VersionManager, res.VersionManagerSignal))
+	fmt.Fprintf(w, "  package manager: %s\n", describeManager(opts.packageManager, pmSet, res.PackageManager, res.PackageManagerSignal))
+	if wantHook {
+		opts, _, _ = mergeDetected(opts, res, vmSet, pmSet)
+	}
+
+	name, err := repo.CanonicalName()
+	if err != nil {
+		return fmt.Errorf("failed to determine repo name: %w", err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	entry := repoEntryFor(repo, opts)
+	status := "new"
+	if existing, ok := cfg.Lookup(name); ok {
+		entry = keepUnsetFields(entry, existing)
+		status = "updated"
+		if existing.Equal(entry) {
+			status = "unchanged"
+		}
+	}
+	encoded, err := config.EncodeRepo(name, entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode config entry: %w", err)
+	}
+	fmt.Fprintf(w, "\nConfig entry (%s):\n%s", status, encoded)
+
+	if !wantHook {
+		fmt.Fprintln(w, "\nHook: none (pass -c, -v, -p, or -w to generate one)")
+		return nil
+	}
+	if !hookHasWork(opts) {
+		fmt.Fprintf(w, "\nHook: %s\n", noHookMsg)
+		return nil
+	}
+
+	hooksDir, err := repo.HooksDir()
+	if err != nil {
+		return err
+	}
+	content, err := hook.Generate(hook.Planned(hooksDir, hookDataFor(repo, opts)))
+	if err != nil {
+		return err
+	}
+	hookPath := filepath.Join(hooksDir, "post-checkout")
+	fmt.Fprintf(w, "\nHook (%s):\n%s", hookPath, content)
+
+	if _, err := os.Stat(hookPath); err != nil {
+		fmt.Fprintln(w, "\nNo hook installed yet.")
+		return nil
+	}
+	diff, err := hook.Diff(hooksDir, content)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		fmt.Fprintln(w, "\nInstalled hook is up to date.")
+		return nil
+	}
+	fmt.Fprintf(w, "\nDiff against installed hook:\n%s", diff)
+	if !opts.force {
+		fmt.Fprintln(w, "note: a hook is already installed; re-run with -f to overwrite it")
+	}
+	return nil
+}
+
+var forgetCmd = &cobra.Command{
+	Use:   "forget <repo>",
+	Short: "Unregister a repo from the gwt config",
+	Long: `Removes a repo from the [repos] table of the gwt config. The repo is
+named by its canonical name ("owner/repo") or a unique short name ("repo").
+
+Nothing on disk is touched: run 'gwt init --uninstall' inside the repo first
+to remove its hook. Warns when a workspace still lists the repo as a member.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeRegisteredRepos,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		name, _, err := cfg.ResolveRepo(args[0])
+		if err != nil {
+			return fmt.Errorf("repo %w", err)
+		}
+		cfg.Unregister(name)
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Printf("Unregistered %s from config\n", name)
+		for _, ws := range cfg.WorkspacesReferencing(name) {
+			fmt.Fprintf(os.Stderr, "warning: workspace %q still lists %s as a member; remove it from [workspaces.%s] members\n", ws, name, ws)
+		}
+		return nil
+	},
+}
+
+var execCmd = &cobra.Command{
+	Use:   "exec [--filter <pattern>] [-j N] [--grouped] [--] <command> [args...]",
+	Short: "Run a command in every worktree",
+	Long: `Runs a command in each worktree of the repo, several at a time, then prints
+a table of each worktree's exit status. Inside a workspace group, it runs in
+each member worktree of that group instead.
+
+  gwt exec -- go test ./...
+  gwt exec git status --short
+  gwt exec --filter 'feat/*' -- pnpm install
+  gwt exec 'make lint && make test'   # a single argument runs through sh -c
+
+Output lines are prefixed with the worktree name as they arrive; --grouped
+prints each worktree's output in one block once it finishes. gwt exec exits
+non-zero if the command failed in any worktree.
+
+--filter matches like a gwt rm pattern: 'feat/*' also matches feat/a/b.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, _ := cmd.Flags().GetString("filter")
+		jobs, _ := cmd.Flags().GetInt("jobs")
+		grouped, _ := cmd.Flags().GetBool("grouped")
+		if filter != "" {
+			if _, err := path.Match(filter, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", filter, err)
+			}
+		}
+		if jobs < 1 {
+			return fmt.Errorf("--jobs must be at least 1")
+		}
+
+		repo, err := git.NewRepo()
+		if err != nil {
+			return err
+		}
+		targets, err := execTargets(repo, filter)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			if filter == "" {
+				return fmt.Errorf("no worktrees to run in")
+			}
+			return fmt.Errorf("no worktrees match %q", filter)
+		}
+
+		results := runExec(os.Stdout, targets, args, jobs, grouped)
+		if failed := printExecSummary(os.Stdout, results); failed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("command failed in %d of %d worktrees", failed, len(results))
+		}
+		return nil
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <name>",
+	Short: "Bring back a worktree removed with 'gwt rm --trash'",
+	Long: `Re-attaches a trashed worktree at the path it was removed from, with its
+index and untracked and ignored files as they were. The name is the branch
+(or directory name) shown by 'gwt trash', or the full trash ID; the most
+recently trashed match wins. A branch deleted since is recreated at the
+commit the worktree was on.
+
+The shell wrapper (from 'gwt shell-init') cds into the restored worktree.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTrashNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		trashDir, err := config.TrashDir()
+		if err != nil {
+			return err
+		}
+		e, err := git.FindTrash(trashDir, args[0])
+		if err != nil {
+			return err
+		}
+		if err := git.RestoreTrash(trashDir, e); err != nil {
+			return err
+		}
+		fmt.Printf("restored worktree %s at %s\n", e.Name(), e.Path)
+		git.WriteCd(e.Path, e.Path)
+		return nil
+	},
+}
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "List worktrees removed with 'gwt rm --trash'",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		trashDir, err := config.TrashDir()
+		if err != nil {
+			return err
+		}
+		entries, err := git.ListTrash(trashDir)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("trash is empty")
+			return nil
+		}
+		nameW := 0
+		for _, e := range entries {
+			nameW = max(nameW, len(e.Name()))
+		}
+		now := time.Now()
+		for _, e := range entries {
+			fmt.Printf("%-*s  %s  (%s ago)\n", nameW, e.Name(), e.Path, git.FormatAge(now.Sub(e.Trashed)))
+		}
+		return nil
+	},
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:   "empty",
+	Short: "Permanently delete trashed worktrees",
+	Long: `Permanently deletes trashed worktrees, or with --older-than only those
+trashed longer ago than the given age (e.g. 7d, 2w, 12h).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		olderThan, _ := cmd.Flags().GetString("older-than")
+		var age time.Duration
+		if olderThan != "" {
+			var err error
+			if age, err = parseAge(olderThan); err != nil {
+				return err
+			}
+		}
+		trashDir, err := config.TrashDir()
+		if err != nil {
+			return err
+		}
+		purged, err := git.PurgeTrash(trashDir, age)
+		for _, e := range purged {
+			fmt.Printf("deleted %s (%s)\n", e.Name(), e.Path)
+		}
+		if err != nil {
+			return err
+		}
+		if len(purged) == 0 {
+			fmt.Println("nothing to delete")
+		}
+		return nil
+	},
+}
+
+// parseAge parses an age like "7d" or "2w", or any time.ParseDuration value.
+func parseAge(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if len(s) > 1 {
+		if unit, ok := units[s[len(s)-1]]; ok {
+			if n, err := strconv.Atoi(s[:len(s)-1]); err == nil && n >= 0 {
+				return time.Duration(n) * unit, nil
+			}
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 7d, 2w or 12h)", s)
+	}
+	return d, nil
+}
+
+// completeTrashNames provides tab-completion of trashed worktree names.
+func completeTrashNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	trashDir, err := config.TrashDir()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	entries, _ := git.ListTrash(trashDir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+var convertCmd = &cobra.Command{
+	Use:   "convert [<dir>]",
+	Short: "Convert a regular clone into the bare-repo worktree layout",
+	Long: `Restructures an existing clone in place into the layout 'gwt clone'
+creates: the .git directory becomes .bare/, a .git file points at it, and the
+current checkout moves to <branch>/ as a linked worktree.
+
+Local branches, stashes, hooks, the index, and uncommitted or untracked files
+are all kept. Existing linked worktrees are repaired to point at .bare/. The
+repo is then registered with bare = true, and a gwt-generated hook is
+regenerated for the new layout.
+
+Run it from the main worktree with no merge, rebase, or bisect in progress.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		plan, err := git.PlanConvert(dir)
+		if err != nil {
+			return err
+		}
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			printConvertPlan(os.Stdout, plan)
+			return nil
+		}
+		if err := git.Convert(plan); err != nil {
+			return fmt.Errorf("convert failed: %w", err)
+		}
+
+		repo := &git.Repo{Dir: plan.Dir, IsBare: true}
+		mainBranch, err := registerConverted(repo)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to register repo in config: %v\n", err)
+		}
+		git.WriteCd(plan.WorktreePath, plan.WorktreePath)
+
+		fmt.Printf("Converted %s; %s is checked out in %s\n", plan.Dir, plan.Branch, plan.WorktreePath)
+		if mainBranch != "" && mainBranch != plan.Branch {
+			if _, statErr := os.Stat(filepath.Join(plan.Dir, git.BranchToDir(mainBranch))); statErr != nil {
+				fmt.Println("Next steps:")
+				fmt.Printf("  gwt add %s   # the hook copies files from the %s worktree\n", mainBranch, mainBranch)
+			}
+		}
+		return nil
+	},
+}
+
+// printConvertPlan reports what `gwt convert` would move, without touching
+// anything.
+func printConvertPlan(w io.Writer, plan git.ConvertPlan) {
+	fmt.Fprintf(w, "Would convert %s:\n", plan.Dir)
+	fmt.Fprintf(w, "  %s -> %s\n", filepath.Join(plan.Dir, ".git"), filepath.Join(plan.Dir, ".bare"))
+	fmt.Fprintf(w, "  checkout of %s -> %s\n", plan.Branch, plan.WorktreePath)
+	for _, l := range plan.Linked {
+		fmt.Fprintf(w, "  repair linked worktree %s\n", l)
+	}
+}
+
+// registerConverted updates a converted repo's config entry to the bare
+// layout, keeping its other settings, and regenerates its hook when the old
+// one was generated by gwt. Returns the repo's main branch.
+func registerConverted(repo *git.Repo) (string, error) {
+	name, err := repo.CanonicalName()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine repo name: %w", err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+	entry, _ := cfg.Lookup(name)
+	entry.Path = repo.Dir
+	entry.Bare = true
+	entry.MainBranch = git.ResolveMainBranch(repo.Dir, entry.MainBranch)
+	cfg.Register(name, entry)
+	if err := cfg.Save(); err != nil {
+		return entry.MainBranch, fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("Registered %s in config\n", name)
+
+	opts := hookOptionsFromEntry(repo, entry)
+	if !hookHasWork(opts) {
+		return entry.MainBranch, nil
+	}
+	// The hook's base path moves from the clone to its main worktree.
+	hooksDir, err := repo.HooksDir()
+	if err != nil {
+		return entry.MainBranch, err
+	}
+	state, _, err := hook.Check(hooksDir, hook.Planned(hooksDir, hookDataFor(repo, opts)))
+	if err == nil && state == hook.StateStale {
+		opts.force = true
+		return entry.MainBranch, setupHook(repo, opts)
+	}
+	warnHookDrift(repo)
+	return entry.MainBranch, nil
+}
+
+// completeRegisteredRepos provides tab-completion of registered repo names.
+func completeRegisteredRepos(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for name := range cfg.Repos {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
 // registerRepo saves a repo to the config, overwriting if the configuration has changed.
-// Used by init and clone to persist the hook configuration.
+// Used by init and clone to persist the hook configuration. A fork named
+// after its upstream falls back to its origin name rather than take over the
+// entry of the upstream's own clone.
 func registerRepo(repo *git.Repo, opts hookOptions) error {
 	name, err := repo.CanonicalName()
 	if err != nil {
@@ -680,17 +2029,24 @@ func registerRepo(repo *git.Repo, opts hookOptions) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	entry := config.RepoEntry{
-		Path:           repo.Dir,
-		Bare:           repo.IsBare,
-		PackageManager: opts.packageManager,
-		VersionManager: opts.versionManager,
-		CopyFiles:      opts.copyFiles,
-		MainBranch:     opts.mainBranch,
+	entry := repoEntryFor(repo, opts)
+	existing, ok := cfg.Lookup(name)
+	if ok && existing.Path != repo.Dir && repo.NamedAfterUpstream() {
+		// A fork cloned next to the project it was forked from keeps its own
+		// entry, under its origin name.
+		if _, statErr := os.Stat(existing.Path); statErr == nil {
+			if err := repo.NameAfterOrigin(); err != nil {
+				return err
+			}
+			fmt.Printf("%s is already registered for %s; naming this fork after its origin instead\n", name, existing.Path)
+			return registerRepo(repo, opts)
+		}
 	}
-
-	if existing, ok := cfg.Lookup(name); ok && existing.Equal(entry) {
-		return nil
+	if ok {
+		entry = keepUnsetFields(entry, existing)
+		if existing.Equal(entry) {
+			return nil
+		}
 	}
 
 	cfg.Register(name, entry)
@@ -703,6 +2059,28 @@ func registerRepo(repo *git.Repo, opts hookOptions) error {
 	return nil
 }
 
+// repoEntryFor builds the config entry registerRepo persists for repo.
+func repoEntryFor(repo *git.Repo, opts hookOptions) config.RepoEntry {
+	return config.RepoEntry{
+		Path:           repo.Dir,
+		Bare:           repo.IsBare,
+		PackageManager: opts.packageManager,
+		VersionManager: opts.versionManager,
+		CopyFiles:      opts.copyFiles,
+		MainBranch:     opts.mainBranch,
+		Sparse:         opts.sparse,
+	}
+}
+
+// keepUnsetFields carries over the fields of existing that only a dedicated
+// flag sets, so re-registering a repo without that flag does not clear them.
+func keepUnsetFields(entry, existing config.RepoEntry) config.RepoEntry {
+	if entry.Sparse == nil {
+		entry.Sparse = existing.Sparse
+	}
+	return entry
+}
+
 // memberSetupDir returns the absolute path of the worktree whose short name
 // matches ref (the setup_cwd), defaulting to the primary's worktree.
 func memberSetupDir(group string, members []config.ResolvedMember, ref string) string {
@@ -722,7 +2100,7 @@ func memberSetupDir(group string, members []config.ResolvedMember, ref string) s
 // runWorkspaceAdd creates a worktree for every workspace member under a shared
 // per-branch group directory, mirroring the branch to followers, then runs the
 // workspace setup command. Returns the primary worktree path to cd into.
-func runWorkspaceAdd(cfg *config.Config, wsName string, ws config.WorkspaceEntry, args []string) (string, error) {
+func runWorkspaceAdd(cfg *config.Config, wsName string, ws config.WorkspaceEntry, args []string, offline bool) (string, error) {
 	members, err := cfg.ResolveMembers(ws)
 	if err != nil {
 		return "", err
@@ -743,15 +2121,16 @@ func runWorkspaceAdd(cfg *config.Config, wsName string, ws config.WorkspaceEntry
 	var created []string
 	for _, m := range members {
 		worktreePath := filepath.Join(group, m.Short)
-		var gitArgs []string
-		if m.IsPrimary {
-			gitArgs = parsed.Build(worktreePath)
-		} else if git.BranchExists(m.Path, parsed.Branch) {
-			gitArgs = []string{worktreePath, parsed.Branch}
-		} else {
-			gitArgs = []string{"-b", parsed.Branch, worktreePath, git.MainBranchRef(m.Path, m.MainBranch)}
+		a := parsed
+		if !m.IsPrimary {
+			if git.BranchExists(m.Path, parsed.Branch) {
+				a = git.AddArgs{Branch: parsed.Branch}
+			} else {
+				mainBranch := git.ResolveLocalMainBranch(m.Path, m.MainBranch)
+				a = git.NewBranchArgs(parsed.Branch, git.MainBranchRef(m.Path, mainBranch))
+			}
 		}
-		if err := git.AddWorktreeAt(m.Path, gitArgs); err != nil {
+		if err := git.AddWorktree(m.Path, a, worktreePath, git.AddOptions{Sparse: m.Sparse, Offline: offline}); err != nil {
 			return "", fmt.Errorf("creating worktree for %s failed: %w\ncreated so far: %v\nrun `gwt rm` from one of them to unwind", m.Name, err, created)
 		}
 		created = append(created, worktreePath)
@@ -836,67 +2215,101 @@ func validateGroup(root, arg, group string) (string, error) {
 	return filepath.Clean(group), nil
 }
 
+// removalOutcome is one worktree's result in a concurrent multi-removal.
+type removalOutcome struct {
+	name    string // how the worktree is reported (member repo or branch)
+	present bool   // false when there was nothing to remove
+	mr      git.MemberRemoval
+}
+
+// removalTotals sums up a multi-removal: how many worktrees were attempted
+// and removed, the space freed, and a "name: error" line per failure.
+func removalTotals(results []removalOutcome) (removed, attempted int, freed string, failures []string) {
+	var totalBytes int64
+	anyApprox := false
+	for _, res := range results {
+		if !res.present {
+			continue
+		}
+		attempted++
+		if res.mr.Err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", res.name, res.mr.Err))
+			continue
+		}
+		removed++
+		totalBytes += res.mr.Freed.Bytes
+		if res.mr.Freed.Skipped > 0 {
+			anyApprox = true
+		}
+	}
+	return removed, attempted, disk.FormatApprox(totalBytes, anyApprox), failures
+}
+
 // runWorkspaceRemove removes every member worktree in the branch group dir
 // concurrently, then cleans the empty group dir if every member succeeded.
 // Removal is best-effort: every present member is attempted even if others
 // fail, and results are aggregated into a summary printed to stdout. Returns
 // the primary's real repo path to cd back into, and a non-nil error when any
 // member failed (for a non-zero exit).
-func runWorkspaceRemove(cfg *config.Config, wsName string, ws config.WorkspaceEntry, group string, keepBranch, force bool) (string, error) {
+func runWorkspaceRemove(cfg *config.Config, wsName string, ws config.WorkspaceEntry, group string, opts git.RemoveOptions) (string, error) {
 	members, err := cfg.ResolveMembers(ws)
 	if err != nil {
 		return "", err
 	}
 
-	type memberResult struct {
-		name    string
-		present bool
-		mr      git.MemberRemoval
+	results := make([]removalOutcome, len(members))
+
+	// Check every member up front so a group is never half-removed because
+	// one member holds work that would be lost.
+	if !opts.Force {
+		var atRisk []string
+		for _, m := range members {
+			if err := git.CheckRemovable(filepath.Join(group, m.Short)); err != nil {
+				atRisk = append(atRisk, fmt.Sprintf("%s: %v", m.Short, err))
+			}
+		}
+		if len(atRisk) > 0 {
+			return "", fmt.Errorf("nothing removed from workspace group %s:\n%s", filepath.Base(group), strings.Join(atRisk, "\n"))
+		}
 	}
-	results := make([]memberResult, len(members))
 
 	var wg sync.WaitGroup
 	for i, m := range members {
 		worktreePath := filepath.Join(group, m.Short)
 		if _, statErr := os.Stat(worktreePath); statErr != nil {
-			results[i] = memberResult{name: m.Short, present: false}
+			results[i] = removalOutcome{name: m.Short, present: false}
 			continue
 		}
 		wg.Add(1)
 		go func(i int, repoDir, worktreePath, name string) {
 			defer wg.Done()
-			results[i] = memberResult{
+			results[i] = removalOutcome{
 				name:    name,
 				present: true,
-				mr:      git.RemoveMemberWorktree(repoDir, worktreePath, keepBranch, force),
+				mr:      git.RemoveMemberWorktree(repoDir, worktreePath, opts),
 			}
 		}(i, m.Path, worktreePath, m.Short)
 	}
 	wg.Wait()
 
 	// Aggregate.
-	var totalBytes int64
-	anyApprox := false
-	removed, attempted := 0, 0
-	var failures []string                 // "repo: reason"
-	keptBranches := map[string][]string{} // branch -> repos
+	removed, attempted, sizeStr, failures := removalTotals(results)
+	keptBranches := map[string][]string{}   // branch -> repos
+	deletedRemotes := map[string][]string{} // remote branch -> repos
+	var keptRemotes []string                // "repo: reason"
 	for _, res := range results {
-		if !res.present {
-			continue
-		}
-		attempted++
-		if res.mr.Err != nil {
-			failures = append(failures, fmt.Sprintf("%s: %v", res.name, res.mr.Err))
+		if !res.present || res.mr.Err != nil {
 			continue
 		}
-		removed++
-		totalBytes += res.mr.Freed.Bytes
-		if res.mr.Freed.Skipped > 0 {
-			anyApprox = true
-		}
 		if res.mr.BranchKept != "" {
 			keptBranches[res.mr.BranchKept] = append(keptBranches[res.mr.BranchKept], res.name)
 		}
+		if res.mr.Remote.Deleted != "" {
+			deletedRemotes[res.mr.Remote.Deleted] = append(deletedRemotes[res.mr.Remote.Deleted], res.name)
+		}
+		if res.mr.Remote.Kept != "" {
+			keptRemotes = append(keptRemotes, fmt.Sprintf("%s: %s", res.name, res.mr.Remote.Kept))
+		}
 	}
 
 	// Clean the empty group dir (best-effort, only if everything removed).
@@ -908,7 +2321,6 @@ func runWorkspaceRemove(cfg *config.Config, wsName string, ws config.WorkspaceEn
 
 	// Report.
 	groupName := filepath.Base(group)
-	sizeStr := disk.FormatApprox(totalBytes, anyApprox)
 	if len(failures) == 0 {
 		fmt.Printf("removed workspace group %s (%d repos) — freed %s\n", groupName, removed, sizeStr)
 	} else {
@@ -921,6 +2333,12 @@ func runWorkspaceRemove(cfg *config.Config, wsName string, ws config.WorkspaceEn
 		fmt.Printf("note: branch %q kept (not fully merged) in: %s\n", branch, strings.Join(repos, ", "))
 		fmt.Printf("      delete with: git -C <repo> branch -D %s\n", branch)
 	}
+	for remote, repos := range deletedRemotes {
+		fmt.Printf("deleted remote branch %s in: %s\n", remote, strings.Join(repos, ", "))
+	}
+	for _, k := range keptRemotes {
+		fmt.Printf("note: %s\n", k)
+	}
 
 	// Primary path to cd back into.
 	primaryPath := members[0].Path
@@ -946,39 +2364,95 @@ func ensureRegistered(repo *git.Repo, name string) error {
 	if _, ok := cfg.Lookup(name); ok {
 		return nil
 	}
-	cfg.Register(name, config.RepoEntry{Path: repo.Dir})
+	entry := config.RepoEntry{Path: repo.Dir}
+	if branch, ok := git.LocalDefaultBranch(repo.Dir); ok {
+		entry.MainBranch = branch
+	}
+	cfg.Register(name, entry)
 	if err := cfg.Save(); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 	return nil
 }
 
-// isSizeFlag reports whether args is exactly the size flag for `gwt ls`.
-func isSizeFlag(args []string) bool {
-	return len(args) == 1 && (args[0] == "-s" || args[0] == "--size")
+// parseListFlags parses the flags gwt itself handles for `gwt ls`: -s/--size
+// and --sort=recent, each at most once. ok is false when args holds anything
+// else, so the command passes through to git untouched.
+func parseListFlags(args []string) (size, recent, ok bool) {
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case (a == "-s" || a == "--size") && !size:
+			size = true
+		case a == "--sort=recent" && !recent:
+			recent = true
+		case a == "--sort" && !recent && i+1 < len(args) && args[i+1] == "recent":
+			recent = true
+			i++
+		default:
+			return false, false, false
+		}
+	}
+	return size, recent, true
 }
 
 func main() {
-	initCmd.Flags().StringP("main", "m", "main", "Set the main branch name")
+	initCmd.Flags().StringP("main", "m", "", "Set the main branch name (default: the remote's HEAD branch)")
 	initCmd.Flags().StringSliceP("copy", "c", nil, "Files to copy to new worktrees (repeatable)")
 	initCmd.Flags().StringP("version-manager", "v", "", "Version manager (asdf or mise)")
 	initCmd.Flags().StringP("package-manager", "p", "", "Package manager (pnpm, npm, or yarn)")
 	initCmd.Flags().BoolP("force", "f", false, "Overwrite existing post-checkout hook")
 	initCmd.Flags().BoolP("with-hook", "w", false, "Auto-detect managers and generate a post-checkout hook")
+	initCmd.Flags().StringSlice("sparse", nil, "Directories to sparse-check out in new worktrees (repeatable)")
+	initCmd.Flags().BoolP("dry-run", "n", false, "Show what init would write, with a diff against the installed hook")
+	initCmd.Flags().Bool("upgrade", false, "Regenerate the post-checkout hook from the registered config")
+	initCmd.Flags().Bool("uninstall", false, "Remove the gwt-generated post-checkout hook (restoring any hook it replaced) and gwt's fetch refspec")
+	initCmd.MarkFlagsMutuallyExclusive("upgrade", "uninstall", "dry-run")
 
-	cloneCmd.Flags().StringP("main", "m", "main", "Set the main branch name")
+	cloneCmd.Flags().StringP("main", "m", "", "Set the main branch name (default: the remote's HEAD branch)")
 	cloneCmd.Flags().StringSliceP("copy", "c", nil, "Files to copy to new worktrees (repeatable)")
 	cloneCmd.Flags().StringP("version-manager", "v", "", "Version manager (asdf or mise)")
 	cloneCmd.Flags().StringP("package-manager", "p", "", "Package manager (pnpm, npm, or yarn)")
 	cloneCmd.Flags().BoolP("with-hook", "w", false, "Auto-detect managers and generate a post-checkout hook")
+	cloneCmd.Flags().String("filter", "", "Partial clone filter, e.g. blob:none")
+	cloneCmd.Flags().Int("depth", 0, "Fetch only the last <depth> commits of history")
+	cloneCmd.Flags().Bool("single-branch", false, "Fetch only the remote's default branch")
+	cloneCmd.Flags().String("upstream", "", "URL of the repo <repository> was forked from, added as the 'upstream' remote")
+	cloneCmd.Flags().String("reference", "", "Borrow objects from a local repository (path or registered repo name)")
+	cloneCmd.Flags().Bool("dissociate", false, "With --reference, copy the borrowed objects so the clone stands alone")
+	cloneCmd.Flags().StringSlice("sparse", nil, "Directories to sparse-check out in new worktrees (repeatable)")
+
+	convertCmd.Flags().BoolP("dry-run", "n", false, "Show what would be moved without changing anything")
+
+	trashEmptyCmd.Flags().String("older-than", "", "Only delete worktrees trashed longer ago than this, e.g. 7d")
+
+	execCmd.Flags().String("filter", "", "Only run in worktrees whose branch matches this glob")
+	execCmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "Number of worktrees to run in at once")
+	execCmd.Flags().Bool("grouped", false, "Print each worktree's output in one block instead of interleaved lines")
+	execCmd.Flags().SetInterspersed(false)
+
+	useCmd.Flags().BoolP("create", "c", false, "Create the worktree when there is none, checking out or creating the branch")
+	useCmd.Flags().Bool("root", false, "Go to the worktree root instead of the current subdirectory's counterpart")
+
+	promptCmd.Flags().String("format", "", "Go template for the output (default: prompt_format from the config)")
+	promptCmd.Flags().String("refresh", "", "Refresh the cached dirty state of this worktree")
+	_ = promptCmd.Flags().MarkHidden("refresh")
 	rootCmd.Version = resolveVersion()
 	rootCmd.AddCommand(addCmd)
 	rootCmd.AddCommand(cloneCmd)
 	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(forgetCmd)
+	rootCmd.AddCommand(convertCmd)
+	trashCmd.AddCommand(trashEmptyCmd)
+	rootCmd.AddCommand(trashCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(execCmd)
 	rootCmd.AddCommand(removeCmd)
 	rootCmd.AddCommand(useCmd)
+	rootCmd.AddCommand(jumpCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(shellInitCmd)
+	rootCmd.AddCommand(promptCmd)
+	rootCmd.AddCommand(setupCmd)
 
 	// Check for pass-through before cobra runs
 	if len(os.Args) > 1 {
@@ -991,9 +2465,9 @@ func main() {
 		}
 
 		known := map[string]bool{
-			"init": true, "add": true, "clone": true, "remove": true, "rm": true,
-			"use": true, "version": true, "shell-init": true,
-			"help": true, "completion": true, "__complete": true,
+			"init": true, "forget": true, "add": true, "clone": true, "convert": true, "remove": true, "rm": true,
+			"use": true, "jump": true, "version": true, "shell-init": true, "restore": true, "trash": true, "exec": true,
+			"prompt": true, "setup": true, "help": true, "completion": true, "__complete": true,
 			"--help": true, "-h": true, "--version": true,
 		}
 
@@ -1010,19 +2484,18 @@ func main() {
 					fmt.Fprintf(os.Stderr, "error: %v\n", err)
 					os.Exit(1)
 				}
+				warnHookDrift(repo)
 				// Enhance the bare `gwt list` (and its `ls` alias) by marking
-				// the active worktree. `-s`/`--size` adds an on-disk size column.
+				// the active worktree. `-s`/`--size` adds an on-disk size column
+				// and `--sort=recent` a last-used column, most recent first.
 				// Any other flags fall through to plain git untouched.
 				if subcmd == "list" {
-					if len(os.Args) == 2 {
-						if err := repo.PrintWorktreeList(); err != nil {
-							fmt.Fprintf(os.Stderr, "error: %v\n", err)
-							os.Exit(git.ExitCode(err))
+					if size, recent, ok := parseListFlags(os.Args[2:]); ok {
+						opts := git.ListOptions{Size: size}
+						if recent {
+							opts.LastUsed = lastUsed()
 						}
-						return
-					}
-					if isSizeFlag(os.Args[2:]) {
-						if err := repo.PrintSizedWorktreeList(); err != nil {
+						if err := repo.PrintWorktreeList(opts); err != nil {
 							fmt.Fprintf(os.Stderr, "error: %v\n", err)
 							os.Exit(git.ExitCode(err))
 						}
@@ -1,15 +1,22 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/nicwestvold/gwt/config"
 	"github.com/nicwestvold/gwt/detect"
 	"github.com/nicwestvold/gwt/git"
+	"github.com/nicwestvold/gwt/hook"
 )
 
 func TestWorktreeBaseDir(t *testing.T) {
@@ -111,10 +118,84 @@ func TestStripKeepBranchFlag(t *testing.T) {
 	}
 }
 
-func TestShellWrapperContainsCommands(t *testing.T) {
-	for _, cmd := range []string{"add", "clone", "rm", "remove", "use"} {
-		if !strings.Contains(shellWrapper, `"`+cmd+`"`) {
-			t.Errorf("shellWrapper missing command %q", cmd)
+func TestStripOffline(t *testing.T) {
+	cleaned, offline := stripOffline([]string{"--offline", "-b", "feat"})
+	if !offline || strings.Join(cleaned, " ") != "-b feat" {
+		t.Errorf("stripOffline() = (%v, %v), want ([-b feat], true)", cleaned, offline)
+	}
+	if _, offline := stripOffline([]string{"feat"}); offline {
+		t.Error("stripOffline() reported offline without the flag")
+	}
+}
+
+func TestStripRoot(t *testing.T) {
+	cleaned, root := stripRoot([]string{"-b", "feat", "--root"})
+	if !root || strings.Join(cleaned, " ") != "-b feat" {
+		t.Errorf("stripRoot() = (%v, %v), want ([-b feat], true)", cleaned, root)
+	}
+}
+
+func TestStripCarry(t *testing.T) {
+	tests := []struct {
+		args             []string
+		want             string
+		carry, untracked bool
+	}{
+		{[]string{"-b", "feat"}, "-b feat", false, false},
+		{[]string{"-b", "feat", "--carry"}, "-b feat", true, false},
+		{[]string{"--carry-untracked", "-b", "feat"}, "-b feat", true, true},
+	}
+	for _, tt := range tests {
+		cleaned, carry, untracked := stripCarry(tt.args)
+		if strings.Join(cleaned, " ") != tt.want || carry != tt.carry || untracked != tt.untracked {
+			t.Errorf("stripCarry(%v) = (%v, %v, %v), want ([%s], %v, %v)", tt.args, cleaned, carry, untracked, tt.want, tt.carry, tt.untracked)
+		}
+	}
+}
+
+func TestStripPR(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    string
+		number  int
+		wantErr bool
+	}{
+		{[]string{"--pr", "123", "--lock"}, "--lock", 123, false},
+		{[]string{"--pr=#45"}, "", 45, false},
+		{[]string{"feat"}, "feat", 0, false},
+		{[]string{"--pr"}, "", 0, true},
+		{[]string{"--pr", "abc"}, "", 0, true},
+		{[]string{"--pr=0"}, "", 0, true},
+	}
+	for _, tt := range tests {
+		cleaned, number, err := stripPR(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("stripPR(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (number != tt.number || strings.Join(cleaned, " ") != tt.want) {
+			t.Errorf("stripPR(%v) = (%v, %d), want (%q, %d)", tt.args, cleaned, number, tt.want, tt.number)
+		}
+	}
+}
+
+func TestShellWrappersApplyDirectives(t *testing.T) {
+	for shell, wrapper := range shellWrappers {
+		if !strings.Contains(wrapper, "GWT_DIRECTIVE_FILE") {
+			t.Errorf("%s wrapper does not set GWT_DIRECTIVE_FILE", shell)
+		}
+		for _, op := range []string{"cd", "export", "unset", "source"} {
+			if !regexp.MustCompile(`["']` + op + `[ "']`).MatchString(wrapper) {
+				t.Errorf("%s wrapper does not handle the %s directive", shell, op)
+			}
+		}
+		// gwt prompt and completion run on every prompt and keypress; they
+		// send no directives, so the wrapper skips the directive file.
+		if !regexp.MustCompile(`prompt.*__complete`).MatchString(wrapper) {
+			t.Errorf("%s wrapper does not run prompt and __complete directly", shell)
+		}
+		if !slices.Contains(shellInitCmd.ValidArgs, shell) {
+			t.Errorf("shell-init does not accept %q", shell)
 		}
 	}
 }
@@ -167,7 +248,7 @@ func TestRunWorkspaceAdd(t *testing.T) {
 		WorktreeRoot: wtRoot,
 	}
 
-	cd, err := runWorkspaceAdd(cfg, "app", ws, []string{"-b", "feat/x"})
+	cd, err := runWorkspaceAdd(cfg, "app", ws, []string{"-b", "feat/x"}, false)
 	if err != nil {
 		t.Fatalf("runWorkspaceAdd error: %v", err)
 	}
@@ -295,12 +376,12 @@ func TestRunWorkspaceRemove(t *testing.T) {
 		WorktreeRoot: wtRoot,
 	}
 
-	if _, err := runWorkspaceAdd(cfg, "app", ws, []string{"-b", "feat/x"}); err != nil {
+	if _, err := runWorkspaceAdd(cfg, "app", ws, []string{"-b", "feat/x"}, false); err != nil {
 		t.Fatalf("setup add failed: %v", err)
 	}
 
 	group := filepath.Join(wtRoot, "feat-x")
-	cd, err := runWorkspaceRemove(cfg, "app", ws, group, false, false)
+	cd, err := runWorkspaceRemove(cfg, "app", ws, group, git.RemoveOptions{})
 	if err != nil {
 		t.Fatalf("runWorkspaceRemove error: %v", err)
 	}
@@ -332,7 +413,7 @@ func TestResolveWorkspaceGroup(t *testing.T) {
 		WorktreeRoot: wtRoot,
 	}
 
-	if _, err := runWorkspaceAdd(cfg, "app", ws, []string{"-b", "feat/x"}); err != nil {
+	if _, err := runWorkspaceAdd(cfg, "app", ws, []string{"-b", "feat/x"}, false); err != nil {
 		t.Fatalf("setup add failed: %v", err)
 	}
 
@@ -450,26 +531,857 @@ func TestMergeDetected(t *testing.T) {
 	})
 }
 
-func TestIsSizeFlag(t *testing.T) {
+func TestParseListFlags(t *testing.T) {
 	cases := []struct {
-		name string
-		args []string
-		want bool
+		name             string
+		args             []string
+		size, recent, ok bool
 	}{
-		{"short", []string{"-s"}, true},
-		{"long", []string{"--size"}, true},
-		{"none", nil, false},
-		{"empty", []string{}, false},
-		{"other flag", []string{"--porcelain"}, false},
-		{"size plus other", []string{"-s", "--porcelain"}, false},
-		{"two size flags", []string{"-s", "--size"}, false},
-		{"positional", []string{"main"}, false},
+		{"short", []string{"-s"}, true, false, true},
+		{"long", []string{"--size"}, true, false, true},
+		{"none", nil, false, false, true},
+		{"empty", []string{}, false, false, true},
+		{"other flag", []string{"--porcelain"}, false, false, false},
+		{"size plus other", []string{"-s", "--porcelain"}, false, false, false},
+		{"two size flags", []string{"-s", "--size"}, false, false, false},
+		{"positional", []string{"main"}, false, false, false},
+		{"sort recent", []string{"--sort=recent"}, false, true, true},
+		{"sort recent separate", []string{"--sort", "recent", "-s"}, true, true, true},
+		{"other sort", []string{"--sort=name"}, false, false, false},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			if got := isSizeFlag(c.args); got != c.want {
-				t.Errorf("isSizeFlag(%v) = %v, want %v", c.args, got, c.want)
+			size, recent, ok := parseListFlags(c.args)
+			if size != c.size || recent != c.recent || ok != c.ok {
+				t.Errorf("parseListFlags(%v) = (%v, %v, %v), want (%v, %v, %v)", c.args, size, recent, ok, c.size, c.recent, c.ok)
+			}
+		})
+	}
+}
+
+func TestPrintInitPlan(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := filepath.Join(t.TempDir(), "app")
+	mainTestInitRepo(t, dir)
+	if err := os.WriteFile(filepath.Join(dir, "pnpm-lock.yaml"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo := &git.Repo{Dir: dir}
+	hookPath := filepath.Join(dir, ".git", "hooks", "post-checkout")
+	if err := os.WriteFile(hookPath, []byte("#!/bin/bash\necho custom\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	opts := hookOptions{mainBranch: "main", copyFiles: []string{".env"}}
+	if err := printInitPlan(&buf, repo, opts, true, false, false); err != nil {
+		t.Fatalf("printInitPlan error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"package manager: pnpm (from pnpm-lock.yaml)",
+		"Config entry (new):",
+		`package_manager = "pnpm"`,
+		"Hook (" + hookPath + "):",
+		"-echo custom",
+		"re-run with -f",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("plan missing %q\n---\n%s", want, out)
+		}
+	}
+
+	// Nothing is written.
+	if got, _ := os.ReadFile(hookPath); string(got) != "#!/bin/bash\necho custom\n" {
+		t.Errorf("hook was modified: %q", got)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load error: %v", err)
+	}
+	if len(cfg.Repos) != 0 {
+		t.Errorf("config was written: %v", cfg.Repos)
+	}
+}
+
+func TestHookOptionsFromEntry(t *testing.T) {
+	repo := &git.Repo{Dir: t.TempDir()}
+	opts := hookOptionsFromEntry(repo, config.RepoEntry{CopyFiles: []string{".env"}, PackageManager: "pnpm"})
+	if opts.mainBranch != "main" {
+		t.Errorf("mainBranch = %q, want main default", opts.mainBranch)
+	}
+	if opts.packageManager != "pnpm" || len(opts.copyFiles) != 1 {
+		t.Errorf("opts = %+v", opts)
+	}
+}
+
+func TestKeepUnsetFields(t *testing.T) {
+	existing := config.RepoEntry{Path: "/p", Sparse: []string{"app"}}
+	if got := keepUnsetFields(config.RepoEntry{Path: "/p"}, existing); !slices.Equal(got.Sparse, []string{"app"}) {
+		t.Errorf("Sparse = %v, want the existing profile kept", got.Sparse)
+	}
+	if got := keepUnsetFields(config.RepoEntry{Path: "/p", Sparse: []string{"lib"}}, existing); !slices.Equal(got.Sparse, []string{"lib"}) {
+		t.Errorf("Sparse = %v, want the new profile", got.Sparse)
+	}
+}
+
+func TestUpgradeHook(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := filepath.Join(t.TempDir(), "app")
+	mainTestInitRepo(t, dir)
+	repo := &git.Repo{Dir: dir}
+
+	if err := upgradeHook(repo); err == nil {
+		t.Fatal("upgradeHook on an unregistered repo succeeded, want error")
+	}
+
+	opts := hookOptions{mainBranch: "main", packageManager: "pnpm"}
+	if err := registerRepo(repo, opts); err != nil {
+		t.Fatalf("registerRepo error: %v", err)
+	}
+	hooksDir := filepath.Join(dir, ".git", "hooks")
+	if err := os.WriteFile(filepath.Join(hooksDir, "post-checkout"), []byte("#!/bin/bash\necho custom\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data := hookDataFor(repo, opts)
+	if state, p, _ := hook.Check(hooksDir, data); driftMessage(state, p, data) == "" {
+		t.Error("driftMessage is empty for a hand-written hook")
+	}
+
+	if err := upgradeHook(repo); err != nil {
+		t.Fatalf("upgradeHook error: %v", err)
+	}
+	if state, _, err := hook.Check(hooksDir, data); err != nil || state != hook.StateCurrent {
+		t.Errorf("after upgrade Check() = %v, %v; want StateCurrent", state, err)
+	}
+}
+
+func TestRegisterRepoForkKeepsUpstreamEntry(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	tmp := t.TempDir()
+	project := filepath.Join(tmp, "project")
+	mainTestInitRepo(t, project)
+	if out, err := exec.Command("git", "-C", project, "remote", "add", "origin", "https://github.com/owner/app.git").CombinedOutput(); err != nil {
+		t.Fatalf("git remote add: %v: %s", err, out)
+	}
+	fork := filepath.Join(tmp, "fork")
+	mainTestInitRepo(t, fork)
+	for _, args := range [][]string{
+		{"remote", "add", "origin", "https://github.com/me/app.git"},
+		{"remote", "add", "upstream", "https://github.com/owner/app.git"},
+		{"config", "gwt.nameRemote", "upstream"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", fork}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	if err := registerRepo(&git.Repo{Dir: project}, hookOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// The fork, cloned with --upstream, would share owner/app; it falls back
+	// to its origin name instead of taking over the project's entry.
+	forkRepo := &git.Repo{Dir: fork}
+	if err := registerRepo(forkRepo, hookOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := cfg.Lookup("owner/app"); e.Path != project {
+		t.Errorf("owner/app path = %q, want %q", e.Path, project)
+	}
+	if e, _ := cfg.Lookup("me/app"); e.Path != fork {
+		t.Errorf("me/app path = %q, want %q", e.Path, fork)
+	}
+
+	// Another plain clone of the project re-registers the name, as before.
+	other := filepath.Join(tmp, "other")
+	mainTestInitRepo(t, other)
+	if out, err := exec.Command("git", "-C", other, "remote", "add", "origin", "https://github.com/owner/app.git").CombinedOutput(); err != nil {
+		t.Fatalf("git remote add: %v: %s", err, out)
+	}
+	if err := registerRepo(&git.Repo{Dir: other}, hookOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := cfg.Lookup("owner/app"); e.Path != other {
+		t.Errorf("owner/app path after a second clone = %q, want %q", e.Path, other)
+	}
+}
+
+func TestRegisterConverted(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := filepath.Join(t.TempDir(), "app")
+	mainTestInitRepo(t, dir)
+	opts := hookOptions{mainBranch: "main", copyFiles: []string{".env"}, force: true}
+	if err := registerRepo(&git.Repo{Dir: dir}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := setupHook(&git.Repo{Dir: dir}, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := git.PlanConvert(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := git.Convert(plan); err != nil {
+		t.Fatal(err)
+	}
+	repo := &git.Repo{Dir: plan.Dir, IsBare: true}
+	mainBranch, err := registerConverted(repo)
+	if err != nil {
+		t.Fatalf("registerConverted error: %v", err)
+	}
+	if mainBranch != "main" {
+		t.Errorf("main branch = %q, want main", mainBranch)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, ok := cfg.Lookup("app")
+	if !ok || !entry.Bare || entry.Path != plan.Dir || len(entry.CopyFiles) != 1 {
+		t.Errorf("entry = %+v, %v; want bare entry keeping copy_files", entry, ok)
+	}
+	hooksDir := filepath.Join(plan.Dir, ".bare", "hooks")
+	if state, _, err := hook.Check(hooksDir, hookDataFor(repo, opts)); err != nil || state != hook.StateCurrent {
+		t.Errorf("hook Check() = %v, %v; want regenerated for the bare layout", state, err)
+	}
+}
+
+func TestResolveReference(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := filepath.Join(t.TempDir(), "app")
+	mainTestInitRepo(t, dir)
+	cfg := &config.Config{Repos: map[string]config.RepoEntry{"acme/app": {Path: dir}}}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	want, err := filepath.EvalSymlinks(filepath.Join(dir, ".git"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ref := range []string{dir, "acme/app", "app"} {
+		got, err := resolveReference(ref)
+		if err != nil {
+			t.Errorf("resolveReference(%q) error: %v", ref, err)
+			continue
+		}
+		if got, _ = filepath.EvalSymlinks(got); got != want {
+			t.Errorf("resolveReference(%q) = %q, want %q", ref, got, want)
+		}
+	}
+	if _, err := resolveReference("acme/missing"); err == nil || !strings.Contains(err.Error(), "not registered") {
+		t.Errorf("resolveReference(unknown) error = %v, want not registered", err)
+	}
+}
+
+func TestForgetCmd(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	cfg := &config.Config{
+		Repos: map[string]config.RepoEntry{
+			"acme/app":   {Path: "/code/app"},
+			"acme/tools": {Path: "/code/tools"},
+		},
+		Workspaces: map[string]config.WorkspaceEntry{
+			"web": {Members: []string{"app", "acme/tools"}},
+		},
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := forgetCmd.RunE(forgetCmd, []string{"app"}); err != nil {
+		t.Fatalf("forget error: %v", err)
+	}
+	loaded, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded.Lookup("acme/app"); ok {
+		t.Error("acme/app still registered after forget")
+	}
+	if _, ok := loaded.Lookup("acme/tools"); !ok {
+		t.Error("acme/tools was removed, want untouched")
+	}
+
+	if err := forgetCmd.RunE(forgetCmd, []string{"app"}); err == nil {
+		t.Error("forgetting an unregistered repo succeeded, want error")
+	}
+}
+
+func TestParseSelection(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []int
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"none", nil, false},
+		{"all", []int{0, 1, 2, 3}, false},
+		{"1,3", []int{0, 2}, false},
+		{"2-4", []int{1, 2, 3}, false},
+		{"4 1, 1", []int{0, 3}, false},
+		{"0", nil, true},
+		{"5", nil, true},
+		{"3-2", nil, true},
+		{"x", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseSelection(tt.input, 4)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSelection(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseSelection(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("parseSelection(%q) = %v, want %v", tt.input, got, tt.want)
+				}
 			}
 		})
 	}
 }
+
+func TestRunInitWizard(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := filepath.Join(t.TempDir(), "app")
+	mainTestInitRepo(t, dir)
+	for name, content := range map[string]string{
+		".gitignore":     ".env*\n",
+		".env":           "A=1",
+		".env.local":     "B=2",
+		"pnpm-lock.yaml": "",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := &git.Repo{Dir: dir}
+
+	// main branch (default), version manager (invalid, then none), package
+	// manager (detected default), copy selection, confirm.
+	input := "\nbogus\nnone\n\n1\ny\n"
+	var out bytes.Buffer
+	if err := runInitWizard(prompter{in: bufio.NewReader(strings.NewReader(input)), out: &out}, repo); err != nil {
+		t.Fatalf("runInitWizard error: %v\n%s", err, out.String())
+	}
+	for _, want := range []string{"package manager: pnpm (from pnpm-lock.yaml)", "1) .env", "2) .env.local", `"bogus" is not one of`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q\n---\n%s", want, out.String())
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, ok := cfg.Lookup("app")
+	if !ok {
+		t.Fatal("repo not registered")
+	}
+	if entry.PackageManager != "pnpm" || entry.VersionManager != "" || len(entry.CopyFiles) != 1 || entry.CopyFiles[0] != ".env" {
+		t.Errorf("entry = %+v", entry)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git", "hooks", "post-checkout")); err != nil {
+		t.Errorf("hook not installed: %v", err)
+	}
+
+	t.Run("declining writes nothing", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+		other := filepath.Join(t.TempDir(), "other")
+		mainTestInitRepo(t, other)
+		var out bytes.Buffer
+		err := runInitWizard(prompter{in: bufio.NewReader(strings.NewReader("\nmise\nnone\nn\n")), out: &out}, &git.Repo{Dir: other})
+		if err != nil {
+			t.Fatalf("runInitWizard error: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(other, ".git", "hooks", "post-checkout")); !os.IsNotExist(err) {
+			t.Error("hook written after declining")
+		}
+	})
+
+	t.Run("end of input aborts", func(t *testing.T) {
+		var out bytes.Buffer
+		err := runInitWizard(prompter{in: bufio.NewReader(strings.NewReader("")), out: &out}, repo)
+		if err != errAborted {
+			t.Errorf("err = %v, want errAborted", err)
+		}
+	})
+}
+
+func TestStripTrash(t *testing.T) {
+	cleaned, trash, noTrash := stripTrash([]string{"--trash", "-k", "feat"})
+	if !trash || noTrash || strings.Join(cleaned, " ") != "-k feat" {
+		t.Errorf("stripTrash() = (%v, %v, %v), want ([-k feat], true, false)", cleaned, trash, noTrash)
+	}
+	if _, trash, noTrash := stripTrash([]string{"--no-trash"}); trash || !noTrash {
+		t.Errorf("stripTrash(--no-trash) = (%v, %v), want (false, true)", trash, noTrash)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"7d", 7 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"d", 0, true},
+		{"soon", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestStripRemote(t *testing.T) {
+	cleaned, remote, forceRemote := stripRemote([]string{"--remote", "--force", "feat"})
+	if !remote || forceRemote || strings.Join(cleaned, " ") != "--force feat" {
+		t.Errorf("stripRemote() = (%v, %v, %v), want ([--force feat], true, false)", cleaned, remote, forceRemote)
+	}
+	cleaned, remote, forceRemote = stripRemote([]string{"--force-remote", "feat"})
+	if !remote || !forceRemote || strings.Join(cleaned, " ") != "feat" {
+		t.Errorf("stripRemote(--force-remote) = (%v, %v, %v), want ([feat], true, true)", cleaned, remote, forceRemote)
+	}
+}
+
+func TestParseBulkRemoveArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    bulkSelector
+		yes     bool
+		rest    string
+		wantErr bool
+	}{
+		{args: []string{"feat/*", "-y", "--force"}, want: bulkSelector{pattern: "feat/*"}, yes: true, rest: "--force"},
+		{args: []string{"--merged", "--older-than", "30d"}, want: bulkSelector{merged: true, olderThan: 30 * 24 * time.Hour}},
+		{args: []string{"--older-than=2w"}, want: bulkSelector{olderThan: 14 * 24 * time.Hour}},
+		{args: []string{"-k", "feat/x"}, rest: "-k feat/x"},
+		{args: []string{"--merged", "feat/x"}, wantErr: true},
+		{args: []string{"a*", "b*"}, wantErr: true},
+		{args: []string{"--older-than"}, wantErr: true},
+		{args: []string{"--older-than", "0d"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		sel, yes, rest, err := parseBulkRemoveArgs(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseBulkRemoveArgs(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (sel != tt.want || yes != tt.yes || strings.Join(rest, " ") != tt.rest) {
+			t.Errorf("parseBulkRemoveArgs(%v) = (%+v, %v, %v), want (%+v, %v, %q)", tt.args, sel, yes, rest, tt.want, tt.yes, tt.rest)
+		}
+	}
+}
+
+func TestMatchBranch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"feat/*", "feat/a", true},
+		{"feat/*", "feat/a/b", true},
+		{"feat/*", "feat", false},
+		{"feat/*", "fix/a", false},
+		{"*-wip", "login-wip", true},
+		{"*-wip", "login-wip/x", true},
+		{"*-wip", "x/login-wip", false},
+		{"*/a", "feat/a", true},
+		{"*/a", "feat/ab", false},
+	}
+	for _, tt := range tests {
+		if got := matchBranch(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchBranch(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRunBulkRemove(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoDir := filepath.Join(root, "repo")
+	mainTestInitRepo(t, repoDir)
+	for _, b := range []string{"feat/a", "feat/b", "fix/c", "feat/dirty"} {
+		wt := filepath.Join(root, strings.ReplaceAll(b, "/", "-"))
+		if err := git.AddWorktree(repoDir, git.NewBranchArgs(b, "main"), wt, git.AddOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// feat/a and feat/dirty are merged, feat/b is not, and feat/new, started
+	// from main afterwards, has no commits of its own.
+	for _, args := range [][]string{
+		{"-C", filepath.Join(root, "feat-a"), "commit", "--quiet", "--allow-empty", "-m", "a"},
+		{"-C", filepath.Join(root, "feat-dirty"), "commit", "--quiet", "--allow-empty", "-m", "dirty"},
+		{"-C", filepath.Join(root, "feat-b"), "commit", "--quiet", "--allow-empty", "-m", "unmerged"},
+		{"-C", repoDir, "merge", "--quiet", "--no-ff", "-m", "merge", "feat/a", "feat/dirty"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if err := git.AddWorktree(repoDir, git.NewBranchArgs("feat/new", "main"), filepath.Join(root, "feat-new"), git.AddOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "feat-dirty", "notes"), []byte("n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo := &git.Repo{Dir: repoDir}
+
+	var out bytes.Buffer
+	p := prompter{in: bufio.NewReader(strings.NewReader("")), out: &out}
+	if err := runBulkRemove(p, repo, bulkSelector{pattern: "feat/*", merged: true}, true, git.RemoveOptions{}, false); err != nil {
+		t.Fatalf("runBulkRemove() error: %v\n%s", err, out.String())
+	}
+	gone := map[string]bool{"feat-a": true}
+	for _, dir := range []string{"feat-a", "feat-b", "fix-c", "feat-dirty", "feat-new"} {
+		_, statErr := os.Stat(filepath.Join(root, dir))
+		if gone[dir] != os.IsNotExist(statErr) {
+			t.Errorf("%s: removed = %v, want %v", dir, os.IsNotExist(statErr), gone[dir])
+		}
+	}
+	if git.BranchExists(repoDir, "feat/a") {
+		t.Error("branch feat/a not deleted")
+	}
+	if !strings.Contains(out.String(), "Skipping 1 worktree(s)") || !strings.Contains(out.String(), "removed 1 worktrees") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	// Without a terminal or --yes, nothing is removed.
+	out.Reset()
+	if err := runBulkRemove(p, repo, bulkSelector{pattern: "fix/*"}, false, git.RemoveOptions{}, false); err == nil {
+		t.Error("runBulkRemove() without --yes succeeded, want refusal")
+	}
+	if _, err := os.Stat(filepath.Join(root, "fix-c")); err != nil {
+		t.Errorf("fix-c removed without confirmation: %v", err)
+	}
+}
+
+func TestRunExec(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(dirB, "fail"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	targets := []execTarget{{name: "a", dir: dirA}, {name: "feat/b", dir: dirB}}
+	argv := []string{"printf 'one\\ntwo'; test ! -e fail"}
+
+	var out bytes.Buffer
+	results := runExec(&out, targets, argv, 1, false)
+	for _, want := range []string{"[a     ] one\n", "[a     ] two\n", "[feat/b] one\n", "[feat/b] two\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("interleaved output missing %q:\n%s", want, out.String())
+		}
+	}
+	if results[0].code != 0 || results[0].err != nil || results[1].code != 1 {
+		t.Errorf("results = %+v, want a ok and feat/b exit 1", results)
+	}
+
+	out.Reset()
+	runExec(&out, targets, argv, 2, true)
+	if !strings.Contains(out.String(), "==> feat/b ("+dirB+") <==\none\ntwo\n") {
+		t.Errorf("grouped output:\n%s", out.String())
+	}
+
+	out.Reset()
+	results = append(results, execResult{target: execTarget{name: "c"}, code: -1, err: errors.New("not found")})
+	if failed := printExecSummary(&out, results); failed != 2 {
+		t.Errorf("printExecSummary() failed = %d, want 2", failed)
+	}
+	for _, want := range []string{"a         ok", "feat/b    exit 1", "c         error", "! not found"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("summary missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestResolveUse(t *testing.T) {
+	cands := []useCandidate{
+		{name: "main", path: "/r"},
+		{name: "fix/login", path: "/wt/fix-login"},
+		{name: "feat/search", path: "/wt/feat-search"},
+		{name: "feat/settings", path: "/wt/feat-settings"},
+		{name: "wip-123", path: "/wt/wip-123"},
+	}
+	tests := []struct {
+		query   string
+		want    string // path, or "" for an error
+		wantErr string
+	}{
+		{"main", "/r", ""},
+		{"fix", "/wt/fix-login", ""},         // prefix
+		{"fix-login", "/wt/fix-login", ""},   // directory form
+		{"login", "/wt/fix-login", ""},       // substring
+		{"FL", "/wt/fix-login", ""},          // fuzzy, case-insensitive
+		{"feat/", "", "matches 2 worktrees"}, // ambiguous prefix
+		{"search", "/wt/feat-search", ""},    // substring beats fuzzy matches
+		{"nope", "", "no worktree found"},
+	}
+	for _, tt := range tests {
+		got, err := resolveUse(cands, tt.query)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("resolveUse(%q) error = %v, want %q", tt.query, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got.path != tt.want {
+			t.Errorf("resolveUse(%q) = %q, %v; want %q", tt.query, got.path, err, tt.want)
+		}
+		if exact := tt.query == "main" || tt.query == "fix-login"; (got.tier == matchExact) != exact {
+			t.Errorf("resolveUse(%q) tier = %d, exact = %v", tt.query, got.tier, exact)
+		}
+	}
+}
+
+func TestPicker(t *testing.T) {
+	p := newPicker([]useCandidate{
+		{name: "main", path: "/r"},
+		{name: "feat/x", path: "/wt/feat-x", dirty: true},
+		{name: "feat/y", path: "/wt/feat-y"},
+	}, nil)
+	if lines := p.render(); len(lines) != 4 || !strings.HasPrefix(lines[1], "> main") || !strings.HasSuffix(lines[2], "[dirty]") {
+		t.Errorf("render() = %q", lines)
+	}
+	for _, k := range "fy" {
+		p.handle(int(k))
+	}
+	if len(p.matches) != 1 || p.matches[0].name != "feat/y" {
+		t.Errorf("matches for %q = %+v, want feat/y", p.query, p.matches)
+	}
+	p.handle(keyBackspace)
+	p.handle(keyDown)
+	chosen, done := p.handle(keyEnter)
+	if !done || chosen == nil || chosen.name != "feat/y" {
+		t.Errorf("Enter after Down on %q chose %+v, want feat/y", p.query, chosen)
+	}
+	if chosen, done := p.handle(keyEsc); !done || chosen != nil {
+		t.Errorf("Esc = %+v, %v; want abort", chosen, done)
+	}
+}
+
+func TestResolveRepoUse(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoDir := filepath.Join(root, "app")
+	mainTestInitRepo(t, repoDir)
+	wt := filepath.Join(root, "feat-x")
+	if err := git.AddWorktree(repoDir, git.NewBranchArgs("feat/x", "main"), wt, git.AddOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{Repos: map[string]config.RepoEntry{"acme/app": {Path: repoDir, MainBranch: "main"}}}
+
+	for arg, want := range map[string]string{"acme/app:feat/x": wt, "app:feat": wt, "app:": repoDir} {
+		c, err := resolveRepoUse(cfg, arg)
+		if err != nil || c.path != want || c.repo != "acme/app" {
+			t.Errorf("resolveRepoUse(%q) = %+v, %v; want %s", arg, c, err, want)
+		}
+	}
+	if _, err := resolveRepoUse(cfg, "other:main"); err == nil {
+		t.Error("resolveRepoUse(unregistered repo) succeeded, want error")
+	}
+	if _, err := resolveRepoUse(cfg, "app:nope"); err == nil || !strings.Contains(err.Error(), "acme/app") {
+		t.Errorf("resolveRepoUse(missing branch) error = %v, want one naming the repo", err)
+	}
+}
+
+func TestRankJump(t *testing.T) {
+	cands := []useCandidate{
+		{repo: "acme/api", name: "feat/login", path: "/api/feat-login"},
+		{repo: "acme/web", name: "feat/login", path: "/web/feat-login"},
+		{repo: "acme/web", name: "login", path: "/web/login"},
+		{repo: "acme/web", name: "main", path: "/web"},
+		{repo: "acme/web", name: "logs", path: "/web/logs"},
+	}
+	scores := map[string]float64{"/web/feat-login": 8, "/api/feat-login": 1}
+
+	var got []string
+	for _, m := range rankJump(cands, scores, "login") {
+		got = append(got, m.path)
+	}
+	want := []string{"/web/login", "/web/feat-login", "/api/feat-login"}
+	if !slices.Equal(got, want) {
+		t.Errorf("rankJump(login) = %v, want %v", got, want)
+	}
+	if ranked := rankJump(cands, scores, "api:feat"); len(ranked) == 0 || ranked[0].path != "/api/feat-login" {
+		t.Errorf("rankJump(api:feat) = %+v, want /api/feat-login first", ranked)
+	}
+	if ranked := rankJump(cands, scores, ""); len(ranked) != 5 || ranked[0].path != "/web/feat-login" {
+		t.Errorf("rankJump(\"\") = %+v, want all, most frecent first", ranked)
+	}
+	// A better kind of match beats frecency.
+	got = nil
+	for _, m := range rankJump(cands, scores, "log") {
+		got = append(got, m.path)
+	}
+	want = []string{"/web/login", "/web/logs", "/web/feat-login", "/api/feat-login"}
+	if !slices.Equal(got, want) {
+		t.Errorf("rankJump(log) = %v, want %v", got, want)
+	}
+
+	// The picker keeps frecency order while filtering.
+	p := newPicker(cands, scores)
+	for _, k := range "feat" {
+		p.handle(int(k))
+	}
+	if len(p.matches) != 2 || p.matches[0].path != "/web/feat-login" {
+		t.Errorf("picker matches for %q = %+v, want /web/feat-login first", p.query, p.matches)
+	}
+}
+
+func TestPreviousWorktree(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	a, b := t.TempDir(), t.TempDir()
+	gone := filepath.Join(t.TempDir(), "gone")
+
+	if _, err := previousWorktree(a); err == nil {
+		t.Error("previousWorktree() with no history succeeded, want error")
+	}
+	cdTo(a)
+	cdTo(b)
+	if got, err := previousWorktree(b); err != nil || got != a {
+		t.Errorf("previousWorktree(b) = %q, %v; want %q", got, err, a)
+	}
+	cdTo(a)
+	if got, err := previousWorktree(a); err != nil || got != b {
+		t.Errorf("previousWorktree(a) = %q, %v; want %q (toggle back)", got, err, b)
+	}
+	// Worktrees that no longer exist or were forgotten are skipped.
+	cdTo(gone)
+	forgetVisits(b)
+	if got, err := previousWorktree(""); err != nil || got != a {
+		t.Errorf("previousWorktree(\"\") = %q, %v; want %q", got, err, a)
+	}
+	// Being sent back to the repo root after a removal is no visit.
+	leaveTo(b)
+	if got, err := previousWorktree(""); err != nil || got != a {
+		t.Errorf("previousWorktree(\"\") after leaveTo = %q, %v; want %q", got, err, a)
+	}
+}
+
+func TestUseOrCreate(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dataHome, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	repoDir := filepath.Join(t.TempDir(), "app")
+	mainTestInitRepo(t, repoDir)
+	repo := &git.Repo{Dir: repoDir}
+
+	path, err := useOrCreate(repo, "feat/new")
+	if err != nil {
+		t.Fatalf("useOrCreate(new branch) error: %v", err)
+	}
+	if want := filepath.Join(dataHome, "gwt", "worktrees", "app", "feat-new"); path != want {
+		t.Errorf("useOrCreate(new branch) = %q, want %q", path, want)
+	}
+	if !git.BranchExists(repoDir, "feat/new") {
+		t.Error("useOrCreate() did not create the branch")
+	}
+
+	// The second time it just finds the worktree.
+	if again, err := useOrCreate(repo, "feat/new"); err != nil || again != path {
+		t.Errorf("useOrCreate(existing worktree) = %q, %v; want %q", again, err, path)
+	}
+}
+
+func TestRenderPrompt(t *testing.T) {
+	cfg := &config.Config{
+		Repos: map[string]config.RepoEntry{"acme/app": {Path: "/src/app", Bare: true}},
+		Workspaces: map[string]config.WorkspaceEntry{
+			"platform": {Members: []string{"acme/app", "acme/api"}},
+		},
+	}
+	tests := []struct {
+		name   string
+		cfg    *config.Config
+		state  git.WorktreeState
+		dirty  bool
+		format string
+		want   string
+	}{
+		{"registered", cfg, git.WorktreeState{RepoDir: "/src/app", Worktree: "/src/app/feat", Branch: "feat"}, true, "", "app@platform:feat*"},
+		{"unregistered", cfg, git.WorktreeState{RepoDir: "/src/tool", Worktree: "/src/tool", Branch: "main", SetupFailed: true}, false, "", "tool:main!"},
+		{"no config", nil, git.WorktreeState{RepoDir: "/src/app", Worktree: "/src/app/feat", Branch: "feat"}, false, "", "app:feat"},
+		{"bare root", cfg, git.WorktreeState{RepoDir: "/src/app"}, false, "", "app@platform"},
+		{"custom", cfg, git.WorktreeState{RepoDir: "/src/app", Worktree: "/src/app/x", Head: "abc1234"}, false,
+			"{{.Worktree}} {{if .Detached}}({{.Branch}}){{end}}", "x (abc1234)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := promptDataFor(tt.cfg, tt.state)
+			d.Dirty = tt.dirty
+			format := tt.format
+			if format == "" {
+				format = defaultPromptFormat
+			}
+			var buf bytes.Buffer
+			if err := renderPrompt(&buf, format, d); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("prompt = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+
+	if err := renderPrompt(&bytes.Buffer{}, "{{.Branch", promptData{}); err == nil {
+		t.Error("renderPrompt() with a broken template succeeded")
+	}
+}
+
+func TestCachedDirty(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	wt := filepath.Join(t.TempDir(), "wt")
+	mainTestInitRepo(t, wt)
+
+	refreshes := 0
+	refresh := func(string) { refreshes++ }
+
+	if cachedDirty(wt, refresh) || refreshes != 1 {
+		t.Errorf("cachedDirty() with no cache = true or %d refreshes; want false and 1", refreshes)
+	}
+	// A refresh is under way, so the next prompt does not start another.
+	cachedDirty(wt, refresh)
+	if refreshes != 1 {
+		t.Errorf("cachedDirty() while refreshing started %d refreshes, want 1", refreshes)
+	}
+
+	if err := os.WriteFile(filepath.Join(wt, "new.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := refreshDirty(wt); err != nil {
+		t.Fatal(err)
+	}
+	if !cachedDirty(wt, refresh) {
+		t.Error("cachedDirty() after refreshing a dirty worktree = false")
+	}
+	if refreshes != 1 {
+		t.Errorf("cachedDirty() with a fresh cache started a refresh")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/nicwestvold/gwt/config"
+	"github.com/nicwestvold/gwt/git"
+)
+
+// defaultPromptFormat renders e.g. "app@platform:feature*!".
+const defaultPromptFormat = `{{.Repo}}{{with .Workspace}}@{{.}}{{end}}{{with .Branch}}:{{.}}{{end}}{{if .Dirty}}*{{end}}{{if .SetupFailed}}!{{end}}`
+
+// promptDirtyTTL is how long a cached dirty state is shown before gwt prompt
+// refreshes it in the background.
+const promptDirtyTTL = 2 * time.Second
+
+// promptData is what a gwt prompt format can show.
+type promptData struct {
+	Repo        string // registered short name, or the repo directory's name
+	Branch      string // branch, or the abbreviated commit when detached
+	Worktree    string // worktree directory name; "" at a bare-repo root
+	Workspace   string // workspace the repo belongs to, if any
+	Detached    bool
+	Dirty       bool // as of the last background refresh
+	SetupFailed bool // the post-checkout hook failed when gwt created the worktree
+}
+
+// promptDataFor describes state using the registered repos and workspaces in
+// cfg (which may be nil). Dirty is left for the caller.
+func promptDataFor(cfg *config.Config, state git.WorktreeState) promptData {
+	d := promptData{
+		Repo:        filepath.Base(state.RepoDir),
+		Branch:      state.Branch,
+		SetupFailed: state.SetupFailed,
+	}
+	if state.Worktree != "" {
+		d.Worktree = filepath.Base(state.Worktree)
+	}
+	if state.Branch == "" && state.Head != "" {
+		d.Branch, d.Detached = state.Head, true
+	}
+	if cfg == nil {
+		return d
+	}
+	for name, e := range cfg.Repos {
+		if e.Path == state.RepoDir {
+			d.Repo = path.Base(name)
+			if ws, _, ok := cfg.WorkspaceForRepo(name); ok {
+				d.Workspace = ws
+			}
+			break
+		}
+	}
+	return d
+}
+
+// renderPrompt executes the text/template format with d.
+func renderPrompt(out io.Writer, format string, d promptData) error {
+	tmpl, err := template.New("prompt").Parse(format)
+	if err != nil {
+		return fmt.Errorf("invalid prompt format: %w", err)
+	}
+	return tmpl.Execute(out, d)
+}
+
+// dirtyCachePath is where the dirty state of worktree is cached: "1" or "0",
+// or empty while the first refresh runs.
+func dirtyCachePath(worktree string) (string, error) {
+	dir, err := config.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(worktree))
+	return filepath.Join(dir, "prompt", hex.EncodeToString(sum[:8])), nil
+}
+
+// cachedDirty returns the cached dirty state of worktree. When the cache is
+// missing or older than promptDirtyTTL it calls refresh (normally
+// refreshDirtyInBackground) and bumps the cache's mtime so the prompts drawn
+// meanwhile don't start more refreshes.
+func cachedDirty(worktree string, refresh func(worktree string)) bool {
+	p, err := dirtyCachePath(worktree)
+	if err != nil {
+		return false
+	}
+	data, _ := os.ReadFile(p)
+	if fi, err := os.Stat(p); err != nil || time.Since(fi.ModTime()) > promptDirtyTTL {
+		now := time.Now()
+		if os.Chtimes(p, now, now) != nil {
+			if os.MkdirAll(filepath.Dir(p), 0o755) != nil || os.WriteFile(p, nil, 0o644) != nil {
+				return false
+			}
+		}
+		refresh(worktree)
+	}
+	return strings.TrimSpace(string(data)) == "1"
+}
+
+// refreshDirtyInBackground starts a detached `gwt prompt --refresh` for
+// worktree and does not wait for it.
+func refreshDirtyInBackground(worktree string) {
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	cmd := exec.Command(exe, "prompt", "--refresh", worktree)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	_ = cmd.Start()
+}
+
+// refreshDirty runs git status in worktree and caches the result.
+func refreshDirty(worktree string) error {
+	p, err := dirtyCachePath(worktree)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	state := "0"
+	if git.IsDirty(worktree) {
+		state = "1"
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), "dirty-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.WriteString(state); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	return os.Rename(tmpName, p)
+}
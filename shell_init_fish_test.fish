@@ -0,0 +1,77 @@
+#!/usr/bin/env fish
+# Integration tests for the gwt fish wrapper function.
+# Usage: fish shell_init_fish_test.fish   (from the repo root)
+
+set -g pass 0
+set -g fail 0
+
+function assert_eq -a label got want
+    if test "$got" = "$want"
+        echo "  PASS: $label"
+        set -g pass (math $pass + 1)
+    else
+        echo "  FAIL: $label (got '$got', want '$want')"
+        set -g fail (math $fail + 1)
+    end
+end
+
+# Generate the wrapper with the real binary, then put a mock gwt first on
+# PATH: it writes $MOCK_DIRECTIVES to GWT_DIRECTIVE_FILE and exits with
+# $MOCK_EXIT.
+set -l wrapper (go run . shell-init fish | string collect)
+or exit 1
+set -l mock_dir (mktemp -d)
+printf '%s\n' '#!/bin/sh' \
+    'if [ -n "${GWT_DIRECTIVE_FILE:-}" ] && [ -n "${MOCK_DIRECTIVES:-}" ]; then' \
+    '    printf "%s\n" "$MOCK_DIRECTIVES" > "$GWT_DIRECTIVE_FILE"' \
+    'fi' \
+    'exit "${MOCK_EXIT:-0}"' > $mock_dir/gwt
+chmod +x $mock_dir/gwt
+set -gx PATH $mock_dir $PATH
+echo $wrapper | source
+set -l start_dir (pwd)
+
+echo "Test: gwt add auto-cd"
+set -l target (mktemp -d)
+set -gx MOCK_DIRECTIVES "cd $target"
+set -gx MOCK_EXIT 0
+gwt add my-feature
+assert_eq "cwd changed to target" (pwd) $target
+cd $start_dir
+
+echo "Test: gwt clone auto-cd"
+gwt clone https://example.com/repo.git
+assert_eq "cwd changed to target" (pwd) $target
+cd $start_dir
+rm -rf $target
+
+echo "Test: gwt list passthrough"
+set -gx MOCK_DIRECTIVES ""
+gwt list
+assert_eq "cwd unchanged" (pwd) $start_dir
+
+echo "Test: export and unset"
+set -gx DEBUG 1
+set -gx MOCK_DIRECTIVES "export COMPOSE_PROJECT_NAME=app feature"\n"unset DEBUG"
+gwt use feature
+assert_eq "variable exported" "$COMPOSE_PROJECT_NAME" "app feature"
+assert_eq "variable unset" (set -q DEBUG; and echo set; or echo unset) unset
+
+echo "Test: source"
+set -l script (mktemp)
+echo 'set -g SOURCED_FROM_GWT yes' > $script
+set -gx MOCK_DIRECTIVES "source $script"
+gwt use feature
+assert_eq "file sourced" "$SOURCED_FROM_GWT" yes
+rm -f $script
+
+echo "Test: non-zero exit preserved"
+set -gx MOCK_DIRECTIVES ""
+set -gx MOCK_EXIT 1
+gwt add failing-branch
+assert_eq "exit code is 1" $status 1
+
+rm -rf $mock_dir
+echo ""
+echo "Results: $pass passed, $fail failed"
+test $fail -eq 0
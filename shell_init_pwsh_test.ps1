@@ -0,0 +1,88 @@
+#!/usr/bin/env pwsh
+# Integration tests for the gwt PowerShell wrapper function.
+# Usage: pwsh -NoProfile -File shell_init_pwsh_test.ps1   (from the repo root)
+
+$ErrorActionPreference = 'Stop'
+$script:pass = 0
+$script:fail = 0
+
+function Assert-Eq($label, $got, $want) {
+    if ("$got" -eq "$want") {
+        Write-Host "  PASS: $label"
+        $script:pass++
+    } else {
+        Write-Host "  FAIL: $label (got '$got', want '$want')"
+        $script:fail++
+    }
+}
+
+# Generate the wrapper with the real binary, then put a mock gwt first on
+# PATH: it writes $MOCK_DIRECTIVES to GWT_DIRECTIVE_FILE and exits with
+# $MOCK_EXIT. Its completion script is a comment, as Invoke-Expression
+# rejects nothing.
+$wrapper = go run . shell-init pwsh | Out-String
+$mockDir = Join-Path ([System.IO.Path]::GetTempPath()) ([System.IO.Path]::GetRandomFileName())
+New-Item -ItemType Directory -Path $mockDir | Out-Null
+$mock = Join-Path $mockDir 'gwt'
+@(
+    '#!/bin/sh'
+    'if [ "$1" = completion ]; then echo "# no completions"; exit 0; fi'
+    'if [ -n "${GWT_DIRECTIVE_FILE:-}" ] && [ -n "${MOCK_DIRECTIVES:-}" ]; then'
+    '    printf "%s\n" "$MOCK_DIRECTIVES" > "$GWT_DIRECTIVE_FILE"'
+    'fi'
+    'exit "${MOCK_EXIT:-0}"'
+) -join "`n" | Set-Content -NoNewline -Path $mock
+chmod +x $mock
+$env:PATH = "$mockDir$([System.IO.Path]::PathSeparator)$env:PATH"
+Invoke-Expression $wrapper
+$startDir = (Get-Location).Path
+
+function New-TempDir {
+    $dir = Join-Path ([System.IO.Path]::GetTempPath()) ([System.IO.Path]::GetRandomFileName())
+    (New-Item -ItemType Directory -Path $dir).FullName
+}
+
+Write-Host "Test: gwt add auto-cd"
+$target = New-TempDir
+$env:MOCK_DIRECTIVES = "cd $target"
+$env:MOCK_EXIT = '0'
+gwt add my-feature
+Assert-Eq "cwd changed to target" (Get-Location).Path $target
+Set-Location $startDir
+
+Write-Host "Test: gwt clone auto-cd"
+gwt clone https://example.com/repo.git
+Assert-Eq "cwd changed to target" (Get-Location).Path $target
+Set-Location $startDir
+Remove-Item -Recurse -Force $target
+
+Write-Host "Test: gwt list passthrough"
+$env:MOCK_DIRECTIVES = ''
+gwt list
+Assert-Eq "cwd unchanged" (Get-Location).Path $startDir
+
+Write-Host "Test: export and unset"
+$env:DEBUG = '1'
+$env:MOCK_DIRECTIVES = "export COMPOSE_PROJECT_NAME=app feature`nunset DEBUG"
+gwt use feature
+Assert-Eq "variable exported" $env:COMPOSE_PROJECT_NAME 'app feature'
+Assert-Eq "variable unset" (Test-Path Env:DEBUG) $false
+
+Write-Host "Test: source"
+$sourceFile = Join-Path (New-TempDir) 'env.ps1'
+Set-Content -Path $sourceFile -Value '$global:SourcedFromGwt = "yes"'
+$env:MOCK_DIRECTIVES = "source $sourceFile"
+gwt use feature
+Assert-Eq "file sourced" $global:SourcedFromGwt 'yes'
+Remove-Item -Recurse -Force (Split-Path $sourceFile)
+
+Write-Host "Test: non-zero exit preserved"
+$env:MOCK_DIRECTIVES = ''
+$env:MOCK_EXIT = '1'
+gwt add failing-branch
+Assert-Eq "exit code is 1" $LASTEXITCODE 1
+
+Remove-Item -Recurse -Force $mockDir
+Write-Host ""
+Write-Host "Results: $($script:pass) passed, $($script:fail) failed"
+if ($script:fail -gt 0) { exit 1 }
@@ -1,6 +1,6 @@
 #!/usr/bin/env bash
 # Integration tests for the gwt shell wrapper function.
-# Usage: bash shell_init_test.sh
+# Usage: bash shell_init_test.sh   (from the repo root)
 
 set -euo pipefail
 
@@ -20,99 +20,101 @@ assert_eq() {
     fi
 }
 
-# Mock gwt binary: writes $MOCK_CD_PATH to GWT_CD_FILE and exits with $MOCK_EXIT.
-mock_gwt() {
-    local bin
-    bin=$(mktemp)
-    cat > "$bin" <<'SCRIPT'
+# The wrapper under test, as printed by the real binary.
+WRAPPER=$(go run . shell-init bash)
+
+# Mock gwt, first on PATH: writes $MOCK_DIRECTIVES to GWT_DIRECTIVE_FILE and
+# exits with $MOCK_EXIT.
+MOCK_DIR=$(mktemp -d)
+cat > "$MOCK_DIR/gwt" <<'SCRIPT'
 #!/usr/bin/env bash
-if [ -n "${GWT_CD_FILE:-}" ] && [ -n "${MOCK_CD_PATH:-}" ]; then
-    printf '%s' "$MOCK_CD_PATH" > "$GWT_CD_FILE"
+if [ -n "${GWT_DIRECTIVE_FILE:-}" ] && [ -n "${MOCK_DIRECTIVES:-}" ]; then
+    printf '%s\n' "$MOCK_DIRECTIVES" > "$GWT_DIRECTIVE_FILE"
 fi
 exit "${MOCK_EXIT:-0}"
 SCRIPT
-    chmod +x "$bin"
-    echo "$bin"
-}
+chmod +x "$MOCK_DIR/gwt"
+export PATH="$MOCK_DIR:$PATH"
 
-# Source the wrapper function, but replace "command gwt" with our mock.
 load_wrapper() {
-    local mock_bin="$1"
-    eval "$(cat <<WRAPPER
-gwt() {
-    if [ "\${1}" = "add" ] || [ "\${1}" = "clone" ]; then
-        local _gwt_cd_file
-        _gwt_cd_file=\$(mktemp)
-        GWT_CD_FILE="\$_gwt_cd_file" "$mock_bin" "\$@"
-        local _gwt_exit=\$?
-        if [ -s "\$_gwt_cd_file" ]; then
-            builtin cd "\$(cat "\$_gwt_cd_file")" || true
-        fi
-        rm -f "\$_gwt_cd_file"
-        return \$_gwt_exit
-    else
-        "$mock_bin" "\$@"
-    fi
-}
-WRAPPER
-)"
+    eval "$WRAPPER"
 }
 
-# --- Test: gwt add writes cd file and wrapper changes directory ---
+# --- Test: gwt add writes a cd directive and wrapper changes directory ---
 echo "Test: gwt add auto-cd"
 (
-    mock_bin=$(mock_gwt)
     target=$(mktemp -d)
-    export MOCK_CD_PATH="$target"
+    export MOCK_DIRECTIVES="cd $target"
     export MOCK_EXIT=0
-    load_wrapper "$mock_bin"
+    load_wrapper
 
     gwt add my-feature
     assert_eq "cwd changed to target" "$(pwd)" "$target"
 
-    rm -f "$mock_bin"
     rm -rf "$target"
 )
 
-# --- Test: gwt clone writes cd file and wrapper changes directory ---
+# --- Test: any command may cd; the wrapper keeps no list ---
 echo "Test: gwt clone auto-cd"
 (
-    mock_bin=$(mock_gwt)
     target=$(mktemp -d)
-    export MOCK_CD_PATH="$target"
+    export MOCK_DIRECTIVES="cd $target"
     export MOCK_EXIT=0
-    load_wrapper "$mock_bin"
+    load_wrapper
 
     gwt clone https://example.com/repo.git
     assert_eq "cwd changed to target" "$(pwd)" "$target"
 
-    rm -f "$mock_bin"
     rm -rf "$target"
 )
 
-# --- Test: gwt list (passthrough) does not attempt cd ---
+# --- Test: gwt list without directives does not cd ---
 echo "Test: gwt list passthrough"
 (
-    mock_bin=$(mock_gwt)
-    export MOCK_CD_PATH="/nonexistent"
+    export MOCK_DIRECTIVES=""
     export MOCK_EXIT=0
-    load_wrapper "$mock_bin"
+    load_wrapper
 
     start_dir=$(pwd)
     gwt list
     assert_eq "cwd unchanged" "$(pwd)" "$start_dir"
+)
 
-    rm -f "$mock_bin"
+# --- Test: export and unset directives change the environment ---
+echo "Test: export and unset"
+(
+    export DEBUG=1
+    export MOCK_DIRECTIVES=$'export COMPOSE_PROJECT_NAME=app feature\nunset DEBUG'
+    export MOCK_EXIT=0
+    load_wrapper
+
+    gwt use feature
+    assert_eq "variable exported" "${COMPOSE_PROJECT_NAME:-}" "app feature"
+    assert_eq "variable unset" "${DEBUG:-unset}" "unset"
+)
+
+# --- Test: source directive runs the file in the shell ---
+echo "Test: source"
+(
+    script=$(mktemp)
+    echo 'SOURCED_FROM_GWT=yes' > "$script"
+    export MOCK_DIRECTIVES="source $script"
+    export MOCK_EXIT=0
+    load_wrapper
+
+    gwt use feature
+    assert_eq "file sourced" "${SOURCED_FROM_GWT:-}" "yes"
+
+    rm -f "$script"
 )
 
 # --- Test: non-zero exit code preserved ---
 echo "Test: non-zero exit preserved"
 (
-    mock_bin=$(mock_gwt)
     target=$(mktemp -d)
-    export MOCK_CD_PATH="$target"
+    export MOCK_DIRECTIVES="cd $target"
     export MOCK_EXIT=1
-    load_wrapper "$mock_bin"
+    load_wrapper
 
     set +e
     gwt add failing-branch
@@ -120,26 +122,11 @@ echo "Test: non-zero exit preserved"
     set -e
     assert_eq "exit code is 1" "$exit_code" "1"
 
-    rm -f "$mock_bin"
     rm -rf "$target"
 )
 
-# --- Test: empty cd file is ignored ---
-echo "Test: empty cd file ignored"
-(
-    mock_bin=$(mock_gwt)
-    export MOCK_CD_PATH=""
-    export MOCK_EXIT=0
-    load_wrapper "$mock_bin"
-
-    start_dir=$(pwd)
-    gwt add my-feature
-    assert_eq "cwd unchanged with empty cd file" "$(pwd)" "$start_dir"
-
-    rm -f "$mock_bin"
-)
-
 # --- Summary ---
+rm -rf "$MOCK_DIR"
 echo ""
 read -r pass fail < "$RESULTS_FILE"
 rm -f "$RESULTS_FILE"
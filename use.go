@@ -0,0 +1,557 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicwestvold/gwt/config"
+	"github.com/nicwestvold/gwt/git"
+)
+
+// useCandidate is a worktree `gwt use` can switch to.
+type useCandidate struct {
+	repo  string // registered repo name, set when choosing across repos
+	name  string // branch, or directory name when detached
+	path  string
+	dirty bool // only filled in for the picker
+}
+
+// label is how the candidate is shown: its name, qualified by its repo when
+// choosing across repos.
+func (c useCandidate) label() string {
+	if c.repo != "" {
+		return c.repo + ":" + c.name
+	}
+	return c.name
+}
+
+// useCandidates returns the worktrees of repo that `gwt use` can switch to:
+// all but the bare repo and missing worktrees.
+func useCandidates(repo *git.Repo) ([]useCandidate, error) {
+	infos, err := repo.ListWorktreesFull()
+	if err != nil {
+		return nil, err
+	}
+	var out []useCandidate
+	for _, w := range infos {
+		if w.Bare || w.Prunable {
+			continue
+		}
+		name := w.Branch
+		if w.Detached {
+			name = filepath.Base(w.Path)
+		}
+		out = append(out, useCandidate{name: name, path: w.Path})
+	}
+	return out, nil
+}
+
+// Match tiers, best first.
+const (
+	matchExact = iota
+	matchPrefix
+	matchSubstring
+	matchFuzzy
+)
+
+// useMatch is a candidate matching a query, with how well it matched.
+type useMatch struct {
+	useCandidate
+	tier int // matchExact ... matchFuzzy
+	gap  int // within matchFuzzy, how many characters the query skipped
+}
+
+// matchCandidate reports how well query matches c's branch, its directory
+// form (feat/x -> feat-x), its directory name or, across repos, its
+// repo:branch label. Only an exact match is case sensitive.
+func matchCandidate(query string, c useCandidate) (useMatch, bool) {
+	best := useMatch{useCandidate: c, tier: -1}
+	q := strings.ToLower(query)
+	keys := []string{c.name, git.BranchToDir(c.name), filepath.Base(c.path)}
+	if c.repo != "" {
+		keys = append(keys, c.label())
+	}
+	for _, key := range keys {
+		k := strings.ToLower(key)
+		tier, gap := -1, 0
+		switch {
+		case key == query:
+			tier = matchExact
+		case strings.HasPrefix(k, q):
+			tier = matchPrefix
+		case strings.Contains(k, q):
+			tier = matchSubstring
+		default:
+			var ok bool
+			if gap, ok = fuzzyGap(q, k); ok {
+				tier = matchFuzzy
+			}
+		}
+		if tier >= 0 && (best.tier < 0 || tier < best.tier || tier == best.tier && gap < best.gap) {
+			best.tier, best.gap = tier, gap
+		}
+	}
+	return best, best.tier >= 0
+}
+
+// fuzzyGap reports whether the characters of q appear in s in order and, if
+// so, how many characters of s lie between the first and last of them.
+func fuzzyGap(q, s string) (int, bool) {
+	if q == "" {
+		return 0, true
+	}
+	start, j := -1, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != q[j] {
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		if j++; j == len(q) {
+			return i + 1 - start - len(q), true
+		}
+	}
+	return 0, false
+}
+
+// rankCandidates returns the candidates matching query, best match first;
+// ties keep their original order. An empty query matches everything.
+func rankCandidates(cands []useCandidate, query string) []useMatch {
+	var out []useMatch
+	for _, c := range cands {
+		if m, ok := matchCandidate(query, c); ok {
+			out = append(out, m)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].tier != out[j].tier {
+			return out[i].tier < out[j].tier
+		}
+		return out[i].gap < out[j].gap
+	})
+	return out
+}
+
+// resolveUse picks the worktree query names: an exact match, else the only
+// candidate in the best matching tier. Several equally good matches are an
+// error listing them.
+func resolveUse(cands []useCandidate, query string) (useMatch, error) {
+	ranked := rankCandidates(cands, query)
+	if len(ranked) == 0 {
+		return useMatch{}, fmt.Errorf("no worktree found for branch %q\nRun 'gwt add %s' to create one", query, query)
+	}
+	var best []useMatch
+	for _, m := range ranked {
+		if m.tier == ranked[0].tier {
+			best = append(best, m)
+		}
+	}
+	if len(best) == 1 || ranked[0].tier == matchExact {
+		return best[0], nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q matches %d worktrees:", query, len(best))
+	for _, m := range best {
+		fmt.Fprintf(&b, "\n  %s  %s", m.label(), m.path)
+	}
+	return useMatch{}, fmt.Errorf("%s", b.String())
+}
+
+// useOrCreate returns the worktree checked out on branch in repo, creating it
+// when there is none: from the local branch, from origin's or upstream's
+// branch of that name, or as a new branch from the main branch. In a workspace the whole
+// branch group is created, as by gwt add.
+func useOrCreate(repo *git.Repo, branch string) (string, error) {
+	path, found, err := repo.FindWorktreeByBranch(branch)
+	if err != nil || found {
+		return path, err
+	}
+
+	if canonical, nameErr := repo.CanonicalName(); nameErr == nil {
+		if cfg, cfgErr := config.Load(); cfgErr == nil {
+			if wsName, ws, ok := cfg.WorkspaceForRepo(canonical); ok {
+				members, err := cfg.ResolveMembers(ws)
+				if err != nil {
+					return "", err
+				}
+				primary := members[0]
+				for _, m := range members {
+					if m.IsPrimary {
+						primary = m
+					}
+				}
+				mainRef := git.MainBranchRef(primary.Path, git.ResolveLocalMainBranch(primary.Path, primary.MainBranch))
+				a := git.CheckoutArgs(primary.Path, branch, mainRef, false)
+				return runWorkspaceAdd(cfg, wsName, ws, a.Args(), false)
+			}
+		}
+	}
+
+	baseDir, entry, err := prepareWorktreeDir(repo)
+	if err != nil {
+		return "", err
+	}
+	mainRef := git.MainBranchRef(repo.Dir, git.ResolveLocalMainBranch(repo.Dir, entry.MainBranch))
+	path = filepath.Join(baseDir, git.BranchToDir(branch))
+	if err := git.AddWorktree(repo.Dir, git.CheckoutArgs(repo.Dir, branch, mainRef, false), path, git.AddOptions{Sparse: entry.Sparse}); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// resolveRepoUse resolves a "<repo>:<branch>" argument: the repo is looked up
+// in the registry by canonical name or unique last segment, and the branch is
+// matched among its worktrees as by resolveUse. An empty branch means the
+// repo's main branch.
+func resolveRepoUse(cfg *config.Config, arg string) (useMatch, error) {
+	ref, query, _ := strings.Cut(arg, ":")
+	name, entry, err := cfg.ResolveRepo(ref)
+	if err != nil {
+		return useMatch{}, err
+	}
+	cands, err := useCandidates(&git.Repo{Dir: entry.Path, IsBare: entry.Bare})
+	if err != nil {
+		return useMatch{}, fmt.Errorf("%s: %w", name, err)
+	}
+	if query == "" {
+		query = git.ResolveLocalMainBranch(entry.Path, entry.MainBranch)
+	}
+	m, err := resolveUse(cands, query)
+	if err != nil {
+		return useMatch{}, fmt.Errorf("%s: %w", name, err)
+	}
+	m.repo = name
+	return m, nil
+}
+
+// jumpCandidates returns the worktrees of every registered repo, by repo
+// name. Repos that can no longer be read are skipped.
+func jumpCandidates(cfg *config.Config) []useCandidate {
+	names := make([]string, 0, len(cfg.Repos))
+	for name := range cfg.Repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var out []useCandidate
+	for _, name := range names {
+		entry := cfg.Repos[name]
+		cands, err := useCandidates(&git.Repo{Dir: entry.Path, IsBare: entry.Bare})
+		if err != nil {
+			continue
+		}
+		for _, c := range cands {
+			c.repo = name
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// rankJump returns the candidates matching query for gwt jump, best first:
+// by how well they matched, then by frecency score, then by how much a fuzzy
+// match skipped. With no scores it ranks as rankCandidates does.
+func rankJump(cands []useCandidate, scores map[string]float64, query string) []useMatch {
+	ranked := rankCandidates(cands, query)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.tier != b.tier {
+			return a.tier < b.tier
+		}
+		if sa, sb := scores[a.path], scores[b.path]; sa != sb {
+			return sa > sb
+		}
+		return a.gap < b.gap
+	})
+	return ranked
+}
+
+// cdTo sends the shell wrapper to path and records the visit in the history.
+func cdTo(path string) {
+	updateHistory(func(h *config.History) { h.Record(path, time.Now()) })
+	git.WriteCd(path, path)
+}
+
+// leaveTo sends the shell wrapper to dir after removing the worktree it was
+// in. Unlike cdTo it records no visit: the user did not choose to go there.
+func leaveTo(dir string) {
+	git.WriteCd(dir, dir)
+}
+
+// cdIntoWorktree is cdTo for switching worktrees: unless root is set, the
+// shell lands in the subdirectory matching the current one (see
+// git.SubdirIn). It returns where the shell goes.
+func cdIntoWorktree(worktree string, root bool) string {
+	dest := worktree
+	if !root {
+		dest = git.SubdirIn(worktree)
+	}
+	updateHistory(func(h *config.History) { h.Record(worktree, time.Now()) })
+	git.WriteCd(dest, worktree)
+	return dest
+}
+
+// forgetVisits drops removed worktrees from the history.
+func forgetVisits(paths ...string) {
+	updateHistory(func(h *config.History) { h.Forget(paths...) })
+}
+
+// updateHistory applies change to the visit history and saves it. The
+// history is a convenience, so failing to update it is only worth a warning.
+func updateHistory(change func(h *config.History)) {
+	h, err := config.LoadHistory()
+	if err == nil {
+		change(h)
+		err = h.Save()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to update visit history: %v\n", err)
+	}
+}
+
+// lastUsed returns when each worktree was last visited, for gwt ls
+// --sort=recent; empty, with a warning, when the history cannot be read.
+func lastUsed() map[string]time.Time {
+	h, err := config.LoadHistory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return map[string]time.Time{}
+	}
+	return h.LastUsed()
+}
+
+// previousWorktree returns the most recently visited worktree other than
+// current that still exists, for gwt use -.
+func previousWorktree(current string) (string, error) {
+	h, err := config.LoadHistory()
+	if err != nil {
+		return "", err
+	}
+	for _, v := range h.Recent() {
+		if v.Path == current {
+			continue
+		}
+		if fi, err := os.Stat(v.Path); err == nil && fi.IsDir() {
+			return v.Path, nil
+		}
+	}
+	return "", fmt.Errorf("no previous worktree to return to")
+}
+
+// fillDirty sets the dirty flag of every candidate, checking them
+// concurrently.
+func fillDirty(cands []useCandidate) {
+	var wg sync.WaitGroup
+	for i := range cands {
+		wg.Add(1)
+		go func(c *useCandidate) {
+			defer wg.Done()
+			c.dirty = git.IsDirty(c.path)
+		}(&cands[i])
+	}
+	wg.Wait()
+}
+
+// pickerRows is how many matches the picker shows at once.
+const pickerRows = 10
+
+// picker is the state of the interactive `gwt use` picker: the query typed
+// so far and the highlighted match.
+type picker struct {
+	cands   []useCandidate
+	scores  map[string]float64 // frecency by path, for gwt jump; nil for gwt use
+	query   string
+	matches []useMatch
+	cursor  int
+}
+
+func newPicker(cands []useCandidate, scores map[string]float64) *picker {
+	p := &picker{cands: cands, scores: scores}
+	p.filter()
+	return p
+}
+
+func (p *picker) filter() {
+	p.matches = rankJump(p.cands, p.scores, p.query)
+	p.cursor = 0
+}
+
+// Keys the picker understands besides printable characters.
+const (
+	keyCtrlC     = 3
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyEnter     = '\r'
+	keyEsc       = 27
+	keyBackspace = 127
+	keyUp        = -1
+	keyDown      = -2
+)
+
+// handle applies one key. done is set when the user picked a worktree
+// (chosen) or aborted (chosen is nil).
+func (p *picker) handle(key int) (chosen *useCandidate, done bool) {
+	switch key {
+	case keyEnter, '\n':
+		if len(p.matches) == 0 {
+			return nil, false
+		}
+		c := p.matches[p.cursor].useCandidate
+		return &c, true
+	case keyCtrlC, keyEsc:
+		return nil, true
+	case keyUp, keyCtrlP:
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case keyDown, keyCtrlN:
+		if p.cursor < min(len(p.matches), pickerRows)-1 {
+			p.cursor++
+		}
+	case keyBackspace, '\b':
+		if p.query != "" {
+			p.query = p.query[:len(p.query)-1]
+			p.filter()
+		}
+	case keyCtrlU:
+		p.query = ""
+		p.filter()
+	default:
+		if key >= ' ' && key < keyBackspace {
+			p.query += string(rune(key))
+			p.filter()
+		}
+	}
+	return nil, false
+}
+
+// render returns the prompt line followed by the visible matches, each
+// showing its branch, path and whether it has uncommitted changes.
+func (p *picker) render() []string {
+	lines := []string{"use> " + p.query}
+	nameW := 0
+	shown := p.matches[:min(len(p.matches), pickerRows)]
+	for _, m := range shown {
+		nameW = max(nameW, len(m.label()))
+	}
+	for i, m := range shown {
+		mark := "  "
+		if i == p.cursor {
+			mark = "> "
+		}
+		dirty := ""
+		if m.dirty {
+			dirty = "  [dirty]"
+		}
+		lines = append(lines, fmt.Sprintf("%s%-*s  %s%s", mark, nameW, m.label(), m.path, dirty))
+	}
+	if len(p.matches) > len(shown) {
+		lines = append(lines, fmt.Sprintf("  ... %d more", len(p.matches)-len(shown)))
+	}
+	if len(p.matches) == 0 {
+		lines = append(lines, "  no matching worktrees")
+	}
+	return lines
+}
+
+// runPicker lets the user choose among cands on the terminal, typing to
+// filter and using the arrow keys (or Ctrl-P/Ctrl-N) to move. Matches are
+// ranked as by rankJump with scores. It returns errAborted on Esc or Ctrl-C.
+func runPicker(cands []useCandidate, scores map[string]float64) (useCandidate, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return useCandidate{}, fmt.Errorf("cannot open the terminal for the picker: %w", err)
+	}
+	defer tty.Close()
+
+	// Read key by key without echo, with Ctrl-C arriving as a key. "time 1"
+	// makes a read give up after a tenth of a second, which tells a lone Esc
+	// from an arrow key sequence.
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		return useCandidate{}, fmt.Errorf("cannot configure the terminal for the picker: %w", err)
+	}
+	if _, err := stty(tty, "-icanon", "-echo", "-isig", "min", "0", "time", "1"); err != nil {
+		return useCandidate{}, fmt.Errorf("cannot configure the terminal for the picker: %w", err)
+	}
+	defer func() { _, _ = stty(tty, saved) }()
+
+	p := newPicker(cands, scores)
+	in := bufio.NewReader(tty)
+	drawPicker(tty, p)
+	for {
+		key, err := readKey(in)
+		if err != nil {
+			return useCandidate{}, err
+		}
+		if key == 0 {
+			continue
+		}
+		chosen, done := p.handle(key)
+		if !done {
+			drawPicker(tty, p)
+			continue
+		}
+		fmt.Fprint(tty, "\r\x1b[J")
+		if chosen == nil {
+			return useCandidate{}, errAborted
+		}
+		return *chosen, nil
+	}
+}
+
+// drawPicker redraws the picker over its previous frame, which starts at the
+// cursor's line, and leaves the cursor at the end of the query.
+func drawPicker(w io.Writer, p *picker) {
+	lines := p.render()
+	var b strings.Builder
+	b.WriteString("\r\x1b[J")
+	b.WriteString(strings.Join(lines, "\r\n"))
+	if below := len(lines) - 1; below > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", below)
+	}
+	fmt.Fprintf(&b, "\r\x1b[%dC", len(lines[0]))
+	_, _ = io.WriteString(w, b.String())
+}
+
+// readKey reads one key press, decoding arrow key escape sequences. It
+// returns 0 when no key arrived before the read timed out.
+func readKey(in *bufio.Reader) (int, error) {
+	c, err := in.ReadByte()
+	if err == io.EOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if c != keyEsc {
+		return int(c), nil
+	}
+	if next, err := in.ReadByte(); err != nil || next != '[' && next != 'O' {
+		return keyEsc, nil
+	}
+	switch c, _ := in.ReadByte(); c {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	}
+	return 0, nil
+}
+
+// stty runs stty with args on the terminal tty and returns its output.
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}